@@ -0,0 +1,225 @@
+package gue
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/oklog/ulid/v2"
+
+	"github.com/vortex14/gue/v7/adapter"
+)
+
+// JobFilter selects the jobs for the Client admin methods, e.g. ListJobs or DeleteJobs.
+// Zero value filter matches all the jobs.
+type JobFilter struct {
+	// ID selects the single job with the given ID, zero value matches any job.
+	ID ulid.ULID
+	// Queues selects the jobs from the given queues only, empty list matches any queue.
+	Queues []string
+	// Type selects the jobs of the given type only, empty value matches any type.
+	Type string
+	// Errored selects the jobs that failed at least once only.
+	Errored bool
+	// CreatedBefore selects the jobs created before the given time only, zero value matches any time.
+	CreatedBefore time.Time
+	// Limit is the maximum number of jobs to select, zero value means no limit.
+	Limit int
+}
+
+func (f JobFilter) where() (string, []any) {
+	var (
+		conds []string
+		args  []any
+	)
+
+	param := func(v any) string {
+		args = append(args, v)
+		return "$" + strconv.Itoa(len(args))
+	}
+
+	if f.ID != (ulid.ULID{}) {
+		conds = append(conds, "job_id = "+param(f.ID.String()))
+	}
+	if len(f.Queues) > 0 {
+		params := make([]string, 0, len(f.Queues))
+		for _, q := range f.Queues {
+			params = append(params, param(q))
+		}
+		conds = append(conds, "queue IN ("+strings.Join(params, ", ")+")")
+	}
+	if f.Type != "" {
+		conds = append(conds, "job_type = "+param(f.Type))
+	}
+	if f.Errored {
+		conds = append(conds, "error_count > 0")
+	}
+	if !f.CreatedBefore.IsZero() {
+		conds = append(conds, "created_at < "+param(f.CreatedBefore.UTC()))
+	}
+
+	if len(conds) == 0 {
+		return "", args
+	}
+
+	return "WHERE " + strings.Join(conds, " AND "), args
+}
+
+func (f JobFilter) limit() string {
+	if f.Limit <= 0 {
+		return ""
+	}
+
+	return " LIMIT " + strconv.Itoa(f.Limit)
+}
+
+// QueueStats is the jobs summary for the queue returned by Client.QueueStats.
+type QueueStats struct {
+	Queue string
+	// Total is the number of all the jobs in the queue.
+	Total int64
+	// Ready is the number of jobs that are eligible for execution, including the ones being worked at the moment.
+	Ready int64
+	// Scheduled is the number of jobs scheduled to be executed in the future, including the errored ones
+	// waiting for the retry.
+	Scheduled int64
+	// Errored is the number of jobs that failed at least once.
+	Errored int64
+	// OldestReadyRunAt is the run at time of the earliest eligible for execution job, zero value if there are
+	// no such jobs in the queue.
+	OldestReadyRunAt time.Time
+}
+
+// QueueStats returns the jobs summary for the given queues or for all the queues that have jobs if none are given.
+//
+// Stats are collected without locking the jobs, so they do not interfere with the workers.
+func (c *Client) QueueStats(ctx context.Context, queues ...string) ([]QueueStats, error) {
+	where, args := JobFilter{Queues: queues}.where()
+	args = append(args, c.now().UTC())
+	now := "$" + strconv.Itoa(len(args))
+
+	query := `SELECT queue,
+  COUNT(*),
+  COUNT(*) FILTER (WHERE run_at <= ` + now + `),
+  COUNT(*) FILTER (WHERE run_at > ` + now + `),
+  COUNT(*) FILTER (WHERE error_count > 0),
+  MIN(run_at) FILTER (WHERE run_at <= ` + now + `)
+FROM gue_jobs
+` + where + `
+GROUP BY queue
+ORDER BY queue`
+
+	rows, err := c.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("could not query queue stats: %w", err)
+	}
+
+	var stats []QueueStats
+	for rows.Next() {
+		var (
+			s      QueueStats
+			oldest sql.NullTime
+		)
+		if err := rows.Scan(&s.Queue, &s.Total, &s.Ready, &s.Scheduled, &s.Errored, &oldest); err != nil {
+			drainRows(rows)
+			return nil, fmt.Errorf("could not scan queue stats: %w", err)
+		}
+
+		if oldest.Valid {
+			s.OldestReadyRunAt = oldest.Time
+		}
+		stats = append(stats, s)
+	}
+
+	return stats, rows.Err()
+}
+
+// drainRows reads the remaining rows, so they are closed and their connection is returned to the pool
+// when the rows are not read till the end, e.g. on scan error. adapter.Rows are closed only once all of them are read.
+func drainRows(rows adapter.Rows) {
+	for rows.Next() {
+		// rows are only read to the end, as adapter.Rows has no Close and is closed once fully read
+		continue
+	}
+}
+
+// ListJobs returns the jobs matching the filter ordered by the run at time.
+//
+// Jobs are selected without locking, so they do not interfere with the workers, but this also means that
+// returned jobs are a snapshot and can not be changed with the Job methods, e.g. Job.Delete returns ErrJobNotLocked.
+// Use RetryJobs and DeleteJobs to change the jobs instead.
+func (c *Client) ListJobs(ctx context.Context, f JobFilter) ([]*Job, error) {
+	where, args := f.where()
+	query := `SELECT job_id, queue, priority, run_at, job_type, args, error_count, last_error, created_at
+FROM gue_jobs
+` + where + `
+ORDER BY run_at ASC, job_id ASC` + f.limit()
+
+	rows, err := c.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("could not list jobs: %w", err)
+	}
+
+	var jobs []*Job
+	for rows.Next() {
+		j := Job{backoff: c.backoff, logger: c.logger, now: c.now}
+		if err := rows.Scan(
+			&j.ID,
+			&j.Queue,
+			&j.Priority,
+			&j.RunAt,
+			&j.Type,
+			&j.Args,
+			&j.ErrorCount,
+			&j.LastError,
+			&j.CreatedAt,
+		); err != nil {
+			drainRows(rows)
+			return nil, fmt.Errorf("could not scan job: %w", err)
+		}
+
+		j.Args, j.traceContext = unwrapTraceArgs(j.Args)
+		jobs = append(jobs, &j)
+	}
+
+	return jobs, rows.Err()
+}
+
+// RetryJobs schedules the jobs matching the filter to be executed immediately and returns the number of
+// affected jobs. Error count and last error are kept, so the job backoff continues from where it was
+// if the job fails again.
+//
+// Jobs that are being worked at the moment are locked by the workers and are skipped.
+func (c *Client) RetryJobs(ctx context.Context, f JobFilter) (int64, error) {
+	where, args := f.where()
+	args = append(args, c.now().UTC())
+	query := `UPDATE gue_jobs
+SET run_at = $` + strconv.Itoa(len(args)) + `, updated_at = $` + strconv.Itoa(len(args)) + `
+WHERE job_id IN (SELECT job_id FROM gue_jobs ` + where + f.limit() + ` FOR UPDATE SKIP LOCKED)`
+
+	ct, err := c.pool.Exec(ctx, query, args...)
+	if err != nil {
+		return 0, fmt.Errorf("could not retry jobs: %w", err)
+	}
+
+	return ct.RowsAffected(), nil
+}
+
+// DeleteJobs deletes the jobs matching the filter and returns the number of deleted jobs.
+//
+// Jobs that are being worked at the moment are locked by the workers and are skipped.
+func (c *Client) DeleteJobs(ctx context.Context, f JobFilter) (int64, error) {
+	where, args := f.where()
+	query := `DELETE FROM gue_jobs
+WHERE job_id IN (SELECT job_id FROM gue_jobs ` + where + f.limit() + ` FOR UPDATE SKIP LOCKED)`
+
+	ct, err := c.pool.Exec(ctx, query, args...)
+	if err != nil {
+		return 0, fmt.Errorf("could not delete jobs: %w", err)
+	}
+
+	return ct.RowsAffected(), nil
+}
@@ -0,0 +1,167 @@
+package gue
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/vortex14/gue/v7/adapter"
+	adapterTesting "github.com/vortex14/gue/v7/adapter/testing"
+)
+
+func TestJobFilter_where(t *testing.T) {
+	where, args := JobFilter{}.where()
+	assert.Empty(t, where)
+	assert.Empty(t, args)
+
+	createdBefore := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	where, args = JobFilter{
+		Queues:        []string{"a", "b"},
+		Type:          "MyJob",
+		Errored:       true,
+		CreatedBefore: createdBefore,
+	}.where()
+	assert.Equal(t, "WHERE queue IN ($1, $2) AND job_type = $3 AND error_count > 0 AND created_at < $4", where)
+	assert.Equal(t, []any{"a", "b", "MyJob", createdBefore}, args)
+}
+
+func TestClientAdmin_ScanErrorDrainsRows(t *testing.T) {
+	ctx := context.Background()
+	scanErr := errors.New("scan failed")
+
+	for name, call := range map[string]func(c *Client) error{
+		"QueueStats": func(c *Client) error {
+			_, err := c.QueueStats(ctx)
+			return err
+		},
+		"ListJobs": func(c *Client) error {
+			_, err := c.ListJobs(ctx, JobFilter{})
+			return err
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			rows := new(adapterTesting.Rows)
+			rows.On("Next").Return(true).Times(3)
+			rows.On("Next").Return(false).Once()
+			rows.On("Scan", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything,
+				mock.Anything, mock.Anything, mock.Anything).Return(scanErr).Maybe()
+			rows.On("Scan", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+				Return(scanErr).Maybe()
+
+			connPool := new(adapterTesting.ConnPool)
+			connPool.Queryable.On("Query", mock.Anything, mock.Anything, mock.Anything).Return(rows, nil).Once()
+
+			c, err := NewClient(connPool)
+			require.NoError(t, err)
+
+			err = call(c)
+			require.ErrorIs(t, err, scanErr)
+			// all the rows are read, so they are closed and the connection is released
+			rows.AssertNumberOfCalls(t, "Next", 4)
+			rows.AssertNumberOfCalls(t, "Scan", 1)
+		})
+	}
+}
+
+func TestClientAdmin(t *testing.T) {
+	for name, openFunc := range adapterTesting.AllAdaptersOpenTestPool {
+		t.Run(name, func(t *testing.T) {
+			testClientAdmin(t, openFunc(t))
+		})
+	}
+}
+
+func testClientAdmin(t *testing.T, connPool adapter.ConnPool) {
+	ctx := context.Background()
+
+	c, err := NewClient(connPool)
+	require.NoError(t, err)
+
+	now := time.Now()
+	enqueue := func(queue, jobType string, runAt time.Time) *Job {
+		j, err := NewJob(jobType, nil, WithJobQueue(queue), WithJobRunAt(runAt))
+		require.NoError(t, err)
+		require.NoError(t, c.Enqueue(ctx, j))
+		return j
+	}
+
+	ready := enqueue("admin-a", "MyJob", now.Add(-time.Minute))
+	scheduled := enqueue("admin-a", "MyJob", now.Add(time.Hour))
+	other := enqueue("admin-b", "OtherJob", now.Add(-time.Minute))
+
+	// fail the job, so it is errored and scheduled for retry
+	j, err := c.LockJobByID(ctx, other.ID)
+	require.NoError(t, err)
+	require.NoError(t, j.Error(ctx, errors.New("boom")))
+	require.NoError(t, j.Done(ctx))
+
+	// worker holds the lock on the ready job, admin methods must not wait for it or change it
+	locked, err := c.LockJobByID(ctx, ready.ID)
+	require.NoError(t, err)
+
+	stats, err := c.QueueStats(ctx)
+	require.NoError(t, err)
+	require.Len(t, stats, 2)
+	assert.Equal(t, "admin-a", stats[0].Queue)
+	assert.Equal(t, int64(2), stats[0].Total)
+	assert.Equal(t, int64(1), stats[0].Ready)
+	assert.Equal(t, int64(1), stats[0].Scheduled)
+	assert.Equal(t, int64(0), stats[0].Errored)
+	assert.False(t, stats[0].OldestReadyRunAt.IsZero())
+	assert.Equal(t, "admin-b", stats[1].Queue)
+	assert.Equal(t, int64(1), stats[1].Errored)
+
+	stats, err = c.QueueStats(ctx, "admin-b", "admin-none")
+	require.NoError(t, err)
+	require.Len(t, stats, 1)
+	assert.Equal(t, "admin-b", stats[0].Queue)
+
+	jobs, err := c.ListJobs(ctx, JobFilter{Queues: []string{"admin-a"}})
+	require.NoError(t, err)
+	require.Len(t, jobs, 2)
+	assert.Equal(t, ready.ID, jobs[0].ID)
+	assert.Equal(t, scheduled.ID, jobs[1].ID)
+	assert.ErrorIs(t, jobs[0].Delete(ctx), ErrJobNotLocked)
+
+	jobs, err = c.ListJobs(ctx, JobFilter{Errored: true})
+	require.NoError(t, err)
+	require.Len(t, jobs, 1)
+	assert.Equal(t, other.ID, jobs[0].ID)
+	assert.Equal(t, int32(1), jobs[0].ErrorCount)
+	assert.Equal(t, "boom", jobs[0].LastError.String)
+
+	jobs, err = c.ListJobs(ctx, JobFilter{Limit: 1})
+	require.NoError(t, err)
+	assert.Len(t, jobs, 1)
+
+	n, err := c.RetryJobs(ctx, JobFilter{Errored: true, Type: "OtherJob"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+
+	retried, err := c.LockJob(ctx, "admin-b")
+	require.NoError(t, err)
+	require.NotNil(t, retried)
+	assert.Equal(t, other.ID, retried.ID)
+	assert.Equal(t, int32(1), retried.ErrorCount)
+	require.NoError(t, retried.Done(ctx))
+
+	n, err = c.DeleteJobs(ctx, JobFilter{ID: ready.ID})
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), n, "locked job must be skipped")
+
+	n, err = c.DeleteJobs(ctx, JobFilter{Queues: []string{"admin-a"}, CreatedBefore: now.Add(time.Minute)})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+
+	require.NoError(t, locked.Delete(ctx))
+	require.NoError(t, locked.Done(ctx))
+
+	jobs, err = c.ListJobs(ctx, JobFilter{Queues: []string{"admin-a"}})
+	require.NoError(t, err)
+	assert.Empty(t, jobs)
+}
@@ -12,6 +12,7 @@ import (
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/metric"
 	"go.opentelemetry.io/otel/metric/noop"
+	"go.opentelemetry.io/otel/propagation"
 
 	"github.com/vortex14/gue/v7/adapter"
 )
@@ -34,6 +35,11 @@ type Client struct {
 	id      string
 	backoff Backoff
 	meter   metric.Meter
+	now     func() time.Time
+
+	splitters map[string]jobSplitter
+
+	tracePropagator propagation.TextMapPropagator
 
 	entropy io.Reader
 
@@ -49,6 +55,7 @@ func NewClient(pool adapter.ConnPool, options ...ClientOption) (*Client, error)
 		id:      RandomStringID(),
 		backoff: DefaultExponentialBackoff,
 		meter:   noop.NewMeterProvider().Meter("noop"),
+		now:     time.Now,
 		entropy: &ulid.LockedMonotonicReader{
 			MonotonicReader: ulid.Monotonic(rand.Reader, 0),
 		},
@@ -64,11 +71,18 @@ func NewClient(pool adapter.ConnPool, options ...ClientOption) (*Client, error)
 }
 
 // Enqueue adds a job to the queue.
+//
+// If the job is split into several jobs by the splitter (see WithClientSplitter), all of them are added
+// within a single transaction.
 func (c *Client) Enqueue(ctx context.Context, j *Job) error {
+	if c.shouldSplit(j) {
+		return c.EnqueueBatch(ctx, []*Job{j})
+	}
+
 	return c.execEnqueue(ctx, j, c.pool)
 }
 
-// EnqueueWithID adds a job to the queue with a specific id
+// EnqueueWithID adds a job to the queue with a specific id. The job is never split by the splitter.
 func (c *Client) EnqueueWithID(ctx context.Context, j *Job, ulid ulid.ULID) error {
 	return c.execEnqueueWithID(ctx, j, c.pool, ulid)
 }
@@ -127,12 +141,34 @@ func (c *Client) EnqueueBatchTx(ctx context.Context, jobs []*Job, tx adapter.Tx)
 	return nil
 }
 
-func (c *Client) execEnqueueWithID(ctx context.Context, j *Job, q adapter.Queryable, jobID ulid.ULID) (err error) {
+const sqlInsertJob = `INSERT INTO gue_jobs
+(job_id, queue, priority, run_at, job_type, args, created_at, updated_at)
+VALUES
+($1, $2, $3, $4, $5, $6, $7, $7)
+`
+
+func (c *Client) execEnqueueWithID(ctx context.Context, j *Job, q adapter.Queryable, jobID ulid.ULID) error {
+	_, err := c.execInsertJob(ctx, j, q, jobID, sqlInsertJob)
+	return err
+}
+
+// execEnqueueIfNotExists adds a job to the queue unless the job with the same ID already exists there.
+// Returns false if the job was not added because of the existing one.
+func (c *Client) execEnqueueIfNotExists(ctx context.Context, j *Job, q adapter.Queryable, jobID ulid.ULID) (bool, error) {
+	ct, err := c.execInsertJob(ctx, j, q, jobID, sqlInsertJob+`ON CONFLICT (job_id) DO NOTHING`)
+	if err != nil {
+		return false, err
+	}
+
+	return ct.RowsAffected() > 0, nil
+}
+
+func (c *Client) execInsertJob(ctx context.Context, j *Job, q adapter.Queryable, jobID ulid.ULID, sql string) (ct adapter.CommandTag, err error) {
 	if j.Type == "" {
-		return ErrMissingType
+		return nil, ErrMissingType
 	}
 
-	j.CreatedAt = time.Now().UTC()
+	j.CreatedAt = c.now().UTC()
 
 	runAt := j.RunAt
 	if runAt.IsZero() {
@@ -146,11 +182,12 @@ func (c *Client) execEnqueueWithID(ctx context.Context, j *Job, q adapter.Querya
 		j.Args = []byte{}
 	}
 
-	_, err = q.Exec(ctx, `INSERT INTO gue_jobs
-(job_id, queue, priority, run_at, job_type, args, created_at, updated_at)
-VALUES
-($1, $2, $3, $4, $5, $6, $7, $7)
-`, idAsString, j.Queue, j.Priority, j.RunAt, j.Type, j.Args, j.CreatedAt)
+	args := j.Args
+	if c.tracePropagator != nil {
+		args = wrapTraceArgs(ctx, c.tracePropagator, j.Args)
+	}
+
+	ct, err = q.Exec(ctx, sql, idAsString, j.Queue, j.Priority, j.RunAt, j.Type, args, j.CreatedAt)
 
 	c.logger.Debug(
 		"Tried to enqueue a job",
@@ -161,10 +198,14 @@ VALUES
 
 	c.mEnqueue.Add(ctx, 1, metric.WithAttributes(attrJobType.String(j.Type), attrSuccess.Bool(err == nil), attrCluster.String(j.Cluster)))
 
-	return err
+	return ct, err
 }
 
 func (c *Client) execEnqueue(ctx context.Context, j *Job, q adapter.Queryable) error {
+	if c.shouldSplit(j) {
+		return c.execEnqueueSplit(ctx, j, q)
+	}
+
 	jobID, err := ulid.New(ulid.Now(), c.entropy)
 	if err != nil {
 		return fmt.Errorf("could not generate new Job ULID ID: %w", err)
@@ -173,12 +214,56 @@ func (c *Client) execEnqueue(ctx context.Context, j *Job, q adapter.Queryable) e
 	return c.execEnqueueWithID(ctx, j, q, jobID)
 }
 
+// shouldSplit checks if the job args exceed the threshold of the splitter registered for the job type.
+func (c *Client) shouldSplit(j *Job) bool {
+	s, ok := c.splitters[j.Type]
+	return ok && len(j.Args) > s.threshold
+}
+
+// execEnqueueSplit splits the job with the splitter registered for the job type and adds the resulting jobs
+// instead of the original one.
+func (c *Client) execEnqueueSplit(ctx context.Context, j *Job, q adapter.Queryable) error {
+	argsList, err := c.splitters[j.Type].split(j.Args)
+	if err != nil {
+		return fmt.Errorf("could not split job: %w", err)
+	}
+	if len(argsList) == 0 {
+		return fmt.Errorf("could not split job: splitter returned no jobs for the job type %q", j.Type)
+	}
+
+	for i, args := range argsList {
+		child := Job{
+			Queue:    j.Queue,
+			Cluster:  j.Cluster,
+			Priority: j.Priority,
+			RunAt:    j.RunAt,
+			Type:     j.Type,
+			Args:     args,
+		}
+
+		jobID, err := ulid.New(ulid.Now(), c.entropy)
+		if err != nil {
+			return fmt.Errorf("could not generate new Job ULID ID: %w", err)
+		}
+
+		// split jobs are never split again, even if their args still exceed the threshold
+		if err := c.execEnqueueWithID(ctx, &child, q, jobID); err != nil {
+			return fmt.Errorf("could not enqueue split job [idx %d]: %w", i, err)
+		}
+	}
+
+	c.logger.Debug("Job was split", adapter.F("job-type", j.Type), adapter.F("jobs", len(argsList)))
+
+	return nil
+}
+
 // LockJob attempts to retrieve a Job from the database in the specified queue.
 // If a job is found, it will be locked on the transactional level, so other workers
 // will be skipping it. If no job is found, nil will be returned instead of an error.
 //
 // This function cares about the priority first to lock top priority jobs first even if there are available ones that
-// should be executed earlier but with the lower priority.
+// should be executed earlier but with the lower priority. Jobs with the same priority are locked in the order
+// of their scheduled time.
 //
 // Because Gue uses transaction-level locks, we have to hold the
 // same transaction throughout the process of getting a job, working it,
@@ -190,9 +275,110 @@ func (c *Client) LockJob(ctx context.Context, queue string) (*Job, error) {
 	sql := `SELECT job_id, queue, priority, run_at, job_type, args, error_count, last_error, created_at
 FROM gue_jobs
 WHERE queue = $1 AND run_at <= $2
+ORDER BY priority ASC, run_at ASC
+LIMIT 1 FOR UPDATE SKIP LOCKED`
+
+	return c.execLockJob(ctx, true, sql, queue, c.now().UTC().Format(time.RFC3339))
+}
+
+// lockJobMinPriority works the same way LockJob does, but skips the jobs with the priority lower than minPriority,
+// i.e. locks only the jobs with Job.Priority <= minPriority, as a lower number means a higher priority.
+func (c *Client) lockJobMinPriority(ctx context.Context, queue string, minPriority JobPriority) (*Job, error) {
+	sql := `SELECT job_id, queue, priority, run_at, job_type, args, error_count, last_error, created_at
+FROM gue_jobs
+WHERE queue = $1 AND run_at <= $2 AND priority <= $3
+ORDER BY priority ASC, run_at ASC
 LIMIT 1 FOR UPDATE SKIP LOCKED`
 
-	return c.execLockJob(ctx, true, sql, queue, time.Now().UTC().Format(time.RFC3339))
+	return c.execLockJob(ctx, true, sql, queue, c.now().UTC().Format(time.RFC3339), minPriority)
+}
+
+// LockJobs attempts to retrieve up to n Jobs from the database in the specified queue at once.
+// Jobs are locked in the same way and order LockJob does, but with the single query and within the single
+// transaction that is shared between all the returned Jobs. If no jobs are found, nil will be returned instead
+// of an error.
+//
+// Every returned Job must be finished with Job.Done() or Job.Error() - shared transaction is committed only
+// when the last Job in the batch is done. Please note that any failed query aborts the shared transaction,
+// so the changes made by the other Jobs in the batch are not persisted and they are released back to the queue.
+func (c *Client) LockJobs(ctx context.Context, queue string, n int) ([]*Job, error) {
+	sql := `SELECT job_id, queue, priority, run_at, job_type, args, error_count, last_error, created_at
+FROM gue_jobs
+WHERE queue = $1 AND run_at <= $2
+ORDER BY priority ASC, run_at ASC
+LIMIT $3 FOR UPDATE SKIP LOCKED`
+
+	return c.execLockJobs(ctx, sql, queue, c.now().UTC(), n)
+}
+
+// lockJobsMinPriority works the same way LockJobs does, but skips the jobs with the priority lower than minPriority.
+func (c *Client) lockJobsMinPriority(ctx context.Context, queue string, n int, minPriority JobPriority) ([]*Job, error) {
+	sql := `SELECT job_id, queue, priority, run_at, job_type, args, error_count, last_error, created_at
+FROM gue_jobs
+WHERE queue = $1 AND run_at <= $2 AND priority <= $4
+ORDER BY priority ASC, run_at ASC
+LIMIT $3 FOR UPDATE SKIP LOCKED`
+
+	return c.execLockJobs(ctx, sql, queue, c.now().UTC(), n, minPriority)
+}
+
+func (c *Client) execLockJobs(ctx context.Context, sql string, args ...any) ([]*Job, error) {
+	tx, err := c.pool.Begin(ctx)
+	if err != nil {
+		c.mLockJob.Add(ctx, 1, metric.WithAttributes(attrJobType.String(""), attrSuccess.Bool(false), attrCluster.String("")))
+		return nil, err
+	}
+
+	jobs, err := c.scanLockedJobs(ctx, tx, sql, args...)
+	if err != nil {
+		rbErr := tx.Rollback(ctx)
+		c.mLockJob.Add(ctx, 1, metric.WithAttributes(attrJobType.String(""), attrSuccess.Bool(false), attrCluster.String("")))
+		return nil, fmt.Errorf("could not lock jobs (rollback result: %v): %w", rbErr, err)
+	}
+
+	if len(jobs) == 0 {
+		return nil, tx.Rollback(ctx)
+	}
+
+	batch := &jobBatch{tx: tx, pending: len(jobs)}
+	for _, j := range jobs {
+		j.batch = batch
+		c.mLockJob.Add(ctx, 1, metric.WithAttributes(attrJobType.String(j.Type), attrSuccess.Bool(true), attrCluster.String(j.Cluster)))
+	}
+
+	return jobs, nil
+}
+
+func (c *Client) scanLockedJobs(ctx context.Context, tx adapter.Tx, sql string, args ...any) ([]*Job, error) {
+	rows, err := tx.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	var jobs []*Job
+	for rows.Next() {
+		j := Job{tx: tx, client: c, backoff: c.backoff, logger: c.logger, now: c.now}
+		if err := rows.Scan(
+			&j.ID,
+			&j.Queue,
+			&j.Priority,
+			&j.RunAt,
+			&j.Type,
+			&j.Args,
+			&j.ErrorCount,
+			&j.LastError,
+			&j.CreatedAt,
+		); err != nil {
+			// rows must be read till the end, otherwise the transaction can not be rolled back
+			drainRows(rows)
+			return nil, err
+		}
+
+		j.Args, j.traceContext = unwrapTraceArgs(j.Args)
+		jobs = append(jobs, &j)
+	}
+
+	return jobs, rows.Err()
 }
 
 // LockJobByID attempts to retrieve a specific Job from the database.
@@ -230,9 +416,32 @@ func (c *Client) LockNextScheduledJob(ctx context.Context, queue string) (*Job,
 	sql := `SELECT job_id, queue, priority, run_at, job_type, args, error_count, last_error, created_at
 FROM gue_jobs
 WHERE queue = $1 AND run_at <= $2
+ORDER BY run_at ASC, priority ASC
+LIMIT 1 FOR UPDATE SKIP LOCKED`
+
+	return c.execLockJob(ctx, true, sql, queue, c.now().UTC())
+}
+
+// lockNextScheduledJobMinPriority works the same way LockNextScheduledJob does, but skips the jobs with the priority
+// lower than minPriority.
+func (c *Client) lockNextScheduledJobMinPriority(ctx context.Context, queue string, minPriority JobPriority) (*Job, error) {
+	sql := `SELECT job_id, queue, priority, run_at, job_type, args, error_count, last_error, created_at
+FROM gue_jobs
+WHERE queue = $1 AND run_at <= $2 AND priority <= $3
+ORDER BY run_at ASC, priority ASC
 LIMIT 1 FOR UPDATE SKIP LOCKED`
 
-	return c.execLockJob(ctx, true, sql, queue, time.Now().UTC())
+	return c.execLockJob(ctx, true, sql, queue, c.now().UTC(), minPriority)
+}
+
+// BindJobTx binds the job to the transaction as if the job was locked by the Client within tx, so the job state
+// changes, e.g. Delete() or Error(), are executed within tx and Done() commits it. This is intended for the custom
+// JobLocker implementations, e.g. guetest.Locker that works jobs without the DB.
+func (c *Client) BindJobTx(j *Job, tx adapter.Tx) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.tx, j.client, j.backoff, j.logger, j.now = tx, c, c.backoff, c.logger, c.now
 }
 
 func (c *Client) execLockJob(ctx context.Context, handleErrNoRows bool, sql string, args ...any) (*Job, error) {
@@ -242,7 +451,7 @@ func (c *Client) execLockJob(ctx context.Context, handleErrNoRows bool, sql stri
 		return nil, err
 	}
 
-	j := Job{tx: tx, backoff: c.backoff, logger: c.logger}
+	j := Job{tx: tx, client: c, backoff: c.backoff, logger: c.logger, now: c.now}
 
 	err = tx.QueryRow(ctx, sql, args...).Scan(
 		&j.ID,
@@ -256,6 +465,7 @@ func (c *Client) execLockJob(ctx context.Context, handleErrNoRows bool, sql stri
 		&j.CreatedAt,
 	)
 	if err == nil {
+		j.Args, j.traceContext = unwrapTraceArgs(j.Args)
 		c.mLockJob.Add(ctx, 1, metric.WithAttributes(attrJobType.String(j.Type), attrSuccess.Bool(true), attrCluster.String(j.Cluster)))
 		return &j, nil
 	}
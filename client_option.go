@@ -1,7 +1,10 @@
 package gue
 
 import (
+	"time"
+
 	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/propagation"
 
 	"github.com/vortex14/gue/v7/adapter"
 )
@@ -37,3 +40,48 @@ func WithClientMeter(meter metric.Meter) ClientOption {
 		c.meter = meter
 	}
 }
+
+// WithClientNowFunc overrides the time source used by the client to stamp enqueued jobs, to decide which jobs are
+// eligible for locking and to calculate errored jobs reschedule time. Defaults to time.Now. Mostly useful in tests
+// in combination with the fake clock from the guetest package.
+func WithClientNowFunc(now func() time.Time) ClientOption {
+	return func(c *Client) {
+		c.now = now
+	}
+}
+
+// WithClientTracePropagator enables trace context propagation from the enqueuing code to the job handler.
+// Trace context from the enqueue context is injected into the job args envelope with the given propagator, e.g.
+// propagation.TraceContext{}, and is extracted by the workers with WithWorkerTracePropagator option set.
+// The envelope is removed from the job args when the job is locked, so handlers always get the original args,
+// and jobs enqueued without the trace context are worked as usual. Disabled by default.
+func WithClientTracePropagator(propagator propagation.TextMapPropagator) ClientOption {
+	return func(c *Client) {
+		c.tracePropagator = propagator
+	}
+}
+
+// Splitter splits oversized job args into the args of several smaller jobs.
+type Splitter func(args []byte) ([][]byte, error)
+
+type jobSplitter struct {
+	split     Splitter
+	threshold int
+}
+
+// WithClientSplitter registers splitter for the jobs of the given type. When the job args size exceeds threshold bytes,
+// the job is not added to the queue as is, but split into several jobs with the args returned by splitter.
+// Resulting jobs inherit queue, priority and run at time of the original one, and are added atomically - within
+// the same transaction. Original job is never added to the queue in this case, so its ID is left empty.
+// Resulting jobs are not split again even if their args still exceed the threshold.
+//
+// Splitter is applied by Enqueue, EnqueueTx, EnqueueBatch and EnqueueBatchTx, but not by EnqueueWithID
+// and WebhookHandler.
+func WithClientSplitter(jobType string, splitter Splitter, threshold int) ClientOption {
+	return func(c *Client) {
+		if c.splitters == nil {
+			c.splitters = make(map[string]jobSplitter)
+		}
+		c.splitters[jobType] = jobSplitter{split: splitter, threshold: threshold}
+	}
+}
@@ -76,3 +76,32 @@ func TestWithClientMeter(t *testing.T) {
 
 	assert.Equal(t, customMeter, clientWithCustomMeter.meter)
 }
+
+func TestWithClientNowFunc(t *testing.T) {
+	clientWithDefaultNow, err := NewClient(nil)
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now(), clientWithDefaultNow.now(), time.Second)
+
+	customNow := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	clientWithCustomNow, err := NewClient(nil, WithClientNowFunc(func() time.Time {
+		return customNow
+	}))
+	require.NoError(t, err)
+	assert.Equal(t, customNow, clientWithCustomNow.now())
+}
+
+func TestWithClientSplitter(t *testing.T) {
+	clientWOutSplitter, err := NewClient(nil)
+	require.NoError(t, err)
+	assert.False(t, clientWOutSplitter.shouldSplit(&Job{Type: "MyJob", Args: []byte("0123456789")}))
+
+	splitter := Splitter(func(args []byte) ([][]byte, error) {
+		return [][]byte{args[:len(args)/2], args[len(args)/2:]}, nil
+	})
+	clientWithSplitter, err := NewClient(nil, WithClientSplitter("MyJob", splitter, 5))
+	require.NoError(t, err)
+
+	assert.False(t, clientWithSplitter.shouldSplit(&Job{Type: "MyJob", Args: []byte("01234")}))
+	assert.True(t, clientWithSplitter.shouldSplit(&Job{Type: "MyJob", Args: []byte("012345")}))
+	assert.False(t, clientWithSplitter.shouldSplit(&Job{Type: "OtherJob", Args: []byte("012345")}))
+}
@@ -11,6 +11,7 @@ import (
 
 	"github.com/oklog/ulid/v2"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 
 	"github.com/vortex14/gue/v7/adapter"
@@ -636,6 +637,84 @@ func testJobPriority(t *testing.T, connPool adapter.ConnPool) {
 	}
 }
 
+func TestJobPriorityRunAtOrder(t *testing.T) {
+	for name, openFunc := range adapterTesting.AllAdaptersOpenTestPool {
+		t.Run(name, func(t *testing.T) {
+			testJobPriorityRunAtOrder(t, openFunc(t))
+		})
+	}
+}
+
+func testJobPriorityRunAtOrder(t *testing.T, connPool adapter.ConnPool) {
+	ctx := context.Background()
+
+	c, err := NewClient(connPool)
+	require.NoError(t, err)
+
+	now := time.Now().Add(-time.Minute)
+	enqueue := func(queue, args string, priority JobPriority, runAtOffset time.Duration) {
+		j, err := NewJob(
+			"MyJob", args,
+			WithJobQueue(queue),
+			WithJobPriority(priority),
+			WithJobRunAt(now.Add(runAtOffset)),
+		)
+		require.NoError(t, err)
+		require.NoError(t, c.Enqueue(ctx, j))
+	}
+
+	// interleaved high and low priority jobs, jobs with the same priority keep run at order
+	for _, queue := range []string{"lock-job", "lock-jobs", "lock-next-scheduled-job"} {
+		enqueue(queue, "low-2", JobPriorityLow, 4*time.Second)
+		enqueue(queue, "high-2", JobPriorityHigh, 3*time.Second)
+		enqueue(queue, "low-1", JobPriorityLow, 2*time.Second)
+		enqueue(queue, "high-1", JobPriorityHigh, time.Second)
+		enqueue(queue, "default", JobPriorityDefault, 0)
+	}
+
+	lockedArgs := func(j *Job) string {
+		var args string
+		require.NoError(t, j.UnmarshalArgs(&args))
+
+		t.Cleanup(func() {
+			err := j.Done(ctx)
+			assert.NoError(t, err)
+		})
+
+		return args
+	}
+
+	var args []string
+	for {
+		j, err := c.LockJob(ctx, "lock-job")
+		require.NoError(t, err)
+		if j == nil {
+			break
+		}
+		args = append(args, lockedArgs(j))
+	}
+	assert.Equal(t, []string{"high-1", "high-2", "default", "low-1", "low-2"}, args)
+
+	jobs, err := c.LockJobs(ctx, "lock-jobs", 10)
+	require.NoError(t, err)
+	args = nil
+	for _, j := range jobs {
+		args = append(args, lockedArgs(j))
+	}
+	assert.Equal(t, []string{"high-1", "high-2", "default", "low-1", "low-2"}, args)
+
+	args = nil
+	for {
+		j, err := c.LockNextScheduledJob(ctx, "lock-next-scheduled-job")
+		require.NoError(t, err)
+		if j == nil {
+			break
+		}
+		args = append(args, lockedArgs(j))
+	}
+	assert.Equal(t, []string{"default", "high-1", "low-1", "high-2", "low-2"}, args)
+}
+
 func findOneJob(t testing.TB, q adapter.Queryable) *Job {
 	t.Helper()
 
@@ -830,3 +909,112 @@ VALUES
 	require.Error(t, err)
 	require.Nil(t, j2)
 }
+
+func TestLockJobs(t *testing.T) {
+	for name, openFunc := range adapterTesting.AllAdaptersOpenTestPool {
+		t.Run(name, func(t *testing.T) {
+			testLockJobs(t, openFunc(t))
+		})
+	}
+}
+
+func TestLockJobs_ScanErrorDrainsRows(t *testing.T) {
+	scanErr := errors.New("scan failed")
+
+	rows := new(adapterTesting.Rows)
+	rows.On("Next").Return(true).Times(3)
+	rows.On("Next").Return(false).Once()
+	rows.On("Scan", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything,
+		mock.Anything, mock.Anything, mock.Anything).Return(scanErr).Once()
+
+	tx := new(adapterTesting.Tx)
+	tx.Queryable.On("Query", mock.Anything, mock.Anything, mock.Anything).Return(rows, nil).Once()
+	tx.Mock.On("Rollback", mock.Anything).Return(nil).Once()
+
+	connPool := new(adapterTesting.ConnPool)
+	connPool.Mock.On("Begin", mock.Anything).Return(tx, nil).Once()
+
+	c, err := NewClient(connPool)
+	require.NoError(t, err)
+
+	jobs, err := c.LockJobs(context.Background(), "", 3)
+	require.ErrorIs(t, err, scanErr)
+	assert.Empty(t, jobs)
+
+	// all the rows are read before the transaction is rolled back
+	rows.AssertNumberOfCalls(t, "Next", 4)
+	rows.AssertExpectations(t)
+	tx.Mock.AssertExpectations(t)
+}
+
+func testLockJobs(t *testing.T, connPool adapter.ConnPool) {
+	ctx := context.Background()
+
+	c, err := NewClient(connPool)
+	require.NoError(t, err)
+
+	jobs, err := c.LockJobs(ctx, "", 3)
+	require.NoError(t, err)
+	require.Empty(t, jobs)
+
+	for i := 0; i < 5; i++ {
+		err := c.Enqueue(ctx, &Job{Type: "MyJob", Args: []byte(fmt.Sprintf("%d", i))})
+		require.NoError(t, err)
+	}
+
+	batch1, err := c.LockJobs(ctx, "", 3)
+	require.NoError(t, err)
+	require.Len(t, batch1, 3)
+
+	// locked jobs are skipped
+	batch2, err := c.LockJobs(ctx, "", 3)
+	require.NoError(t, err)
+	require.Len(t, batch2, 2)
+
+	batch3, err := c.LockJobs(ctx, "", 3)
+	require.NoError(t, err)
+	require.Empty(t, batch3)
+
+	// all the jobs in the batch share the same transaction
+	for _, j := range batch1 {
+		assert.Same(t, batch1[0].Tx(), j.Tx())
+	}
+
+	err = batch1[0].Delete(ctx)
+	require.NoError(t, err)
+	err = batch1[0].Done(ctx)
+	require.NoError(t, err)
+	err = batch1[1].Error(ctx, errors.New("the error msg"))
+	require.NoError(t, err)
+
+	// transaction is not committed until the last job in the batch is done, so the jobs are still locked
+	j, err := c.LockJobByID(ctx, batch1[1].ID)
+	require.Error(t, err)
+	require.Nil(t, j)
+
+	err = batch1[2].Done(ctx)
+	require.NoError(t, err)
+
+	for _, j := range batch2 {
+		err := j.Done(ctx)
+		require.NoError(t, err)
+	}
+
+	_, err = c.LockJobByID(ctx, batch1[0].ID)
+	require.Error(t, err, "deleted job must not be found")
+
+	jErrored, err := c.LockJobByID(ctx, batch1[1].ID)
+	require.NoError(t, err)
+	require.NotNil(t, jErrored)
+	assert.Equal(t, int32(1), jErrored.ErrorCount)
+	assert.Equal(t, "the error msg", jErrored.LastError.String)
+	require.NoError(t, jErrored.Done(ctx))
+
+	// not changed jobs are released back to the queue
+	jobs, err = c.LockJobs(ctx, "", 10)
+	require.NoError(t, err)
+	require.Len(t, jobs, 3)
+	for _, j := range jobs {
+		require.NoError(t, j.Done(ctx))
+	}
+}
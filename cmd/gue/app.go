@@ -0,0 +1,203 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/vortex14/gue/v7"
+	"github.com/vortex14/gue/v7/adapter"
+	"github.com/vortex14/gue/v7/adapter/pgxv5"
+)
+
+const (
+	exitOK      = 0
+	exitFailure = 1
+	exitUsage   = 2
+)
+
+// errUsage is returned by the commands on invalid flags, usage is printed in this case.
+var errUsage = errors.New("invalid usage")
+
+type command struct {
+	summary string
+	run     func(ctx context.Context, a *app, args []string) error
+}
+
+var commands = map[string]command{
+	"enqueue": {summary: "enqueue new job", run: runEnqueue},
+	"stats":   {summary: "show queues summary", run: runStats},
+	"list":    {summary: "list jobs", run: runList},
+	"retry":   {summary: "schedule jobs to be executed immediately", run: runRetry},
+	"delete":  {summary: "delete the job", run: runDelete},
+	"purge":   {summary: "delete jobs in bulk", run: runPurge},
+}
+
+type connectFunc func(ctx context.Context, dbURL string) (adapter.ConnPool, error)
+
+type app struct {
+	stdout  io.Writer
+	stderr  io.Writer
+	getenv  func(string) string
+	connect connectFunc
+	now     func() time.Time
+}
+
+func newApp() *app {
+	return &app{
+		stdout:  os.Stdout,
+		stderr:  os.Stderr,
+		getenv:  os.Getenv,
+		connect: connectPGXv5,
+		now:     time.Now,
+	}
+}
+
+func connectPGXv5(ctx context.Context, dbURL string) (adapter.ConnPool, error) {
+	pool, err := pgxpool.New(ctx, dbURL)
+	if err != nil {
+		return nil, err
+	}
+
+	return pgxv5.NewConnPool(pool), nil
+}
+
+func (a *app) run(ctx context.Context, args []string) int {
+	if len(args) == 0 || args[0] == "-h" || args[0] == "--help" || args[0] == "help" {
+		a.usage()
+		if len(args) == 0 {
+			return exitUsage
+		}
+		return exitOK
+	}
+
+	cmd, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprintf(a.stderr, "gue: unknown command %q\n\n", args[0])
+		a.usage()
+		return exitUsage
+	}
+
+	err := cmd.run(ctx, a, args[1:])
+	switch {
+	case err == nil:
+		return exitOK
+	case errors.Is(err, flag.ErrHelp):
+		return exitOK
+	case errors.Is(err, errUsage):
+		fmt.Fprintf(a.stderr, "gue %s: %v\n", args[0], err)
+		return exitUsage
+	default:
+		fmt.Fprintf(a.stderr, "gue %s: %v\n", args[0], err)
+		return exitFailure
+	}
+}
+
+func (a *app) usage() {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintln(a.stderr, "Usage: gue <command> [flags]")
+	fmt.Fprintln(a.stderr)
+	fmt.Fprintln(a.stderr, "Commands:")
+	for _, name := range names {
+		fmt.Fprintf(a.stderr, "  %-8s %s\n", name, commands[name].summary)
+	}
+	fmt.Fprintln(a.stderr)
+	fmt.Fprintln(a.stderr, `Run "gue <command> --help" for the command flags.`)
+}
+
+// commonFlags are the flags available for all the commands.
+type commonFlags struct {
+	dbURL string
+	json  bool
+}
+
+func (a *app) flagSet(name string) (*flag.FlagSet, *commonFlags) {
+	fs := flag.NewFlagSet("gue "+name, flag.ContinueOnError)
+	fs.SetOutput(a.stderr)
+
+	dbURL := a.getenv("GUE_DATABASE_URL")
+	if dbURL == "" {
+		dbURL = a.getenv("DATABASE_URL")
+	}
+
+	cf := new(commonFlags)
+	fs.StringVar(&cf.dbURL, "db-url", dbURL, "database URL, defaults to GUE_DATABASE_URL or DATABASE_URL env var")
+	fs.BoolVar(&cf.json, "json", false, "print output as JSON")
+
+	return fs, cf
+}
+
+func (a *app) parse(fs *flag.FlagSet, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		return fmt.Errorf("%w: %v", errUsage, err)
+	}
+
+	if fs.NArg() > 0 {
+		return fmt.Errorf("%w: unexpected arguments %q", errUsage, fs.Args())
+	}
+
+	return nil
+}
+
+// client connects to the database and builds gue.Client, the returned func closes the connection.
+func (a *app) client(ctx context.Context, cf *commonFlags) (*gue.Client, func(), error) {
+	if cf.dbURL == "" {
+		return nil, nil, fmt.Errorf("%w: database URL is not set, use --db-url flag or GUE_DATABASE_URL env var", errUsage)
+	}
+
+	pool, err := a.connect(ctx, cf.dbURL)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not connect to the database: %w", err)
+	}
+
+	closePool := func() {
+		if err := pool.Close(); err != nil {
+			fmt.Fprintf(a.stderr, "gue: could not close the database connection: %v\n", err)
+		}
+	}
+
+	c, err := gue.NewClient(pool)
+	if err != nil {
+		closePool()
+		return nil, nil, fmt.Errorf("could not create client: %w", err)
+	}
+
+	return c, closePool, nil
+}
+
+func (a *app) printJSON(v any) error {
+	enc := json.NewEncoder(a.stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// stringsFlag is the repeatable string flag, e.g. --queue a --queue b.
+type stringsFlag []string
+
+// String implements flag.Value.
+func (f *stringsFlag) String() string {
+	return strings.Join(*f, ",")
+}
+
+// Set implements flag.Value.
+func (f *stringsFlag) Set(v string) error {
+	*f = append(*f, v)
+	return nil
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/vortex14/gue/v7"
+	"github.com/vortex14/gue/v7/adapter"
+	adapterTesting "github.com/vortex14/gue/v7/adapter/testing"
+)
+
+// testPool does not close the test pool, as it is closed by the test cleanup.
+type testPool struct {
+	adapter.ConnPool
+}
+
+// Close implements adapter.ConnPool.Close().
+func (testPool) Close() error {
+	return nil
+}
+
+func newTestApp(env map[string]string, connect connectFunc) (*app, *bytes.Buffer, *bytes.Buffer) {
+	stdout, stderr := new(bytes.Buffer), new(bytes.Buffer)
+	return &app{
+		stdout:  stdout,
+		stderr:  stderr,
+		getenv:  func(key string) string { return env[key] },
+		connect: connect,
+		now:     time.Now,
+	}, stdout, stderr
+}
+
+func TestApp_Usage(t *testing.T) {
+	connectErr := errors.New("connect must not be called")
+	failConnect := func(context.Context, string) (adapter.ConnPool, error) {
+		return nil, connectErr
+	}
+
+	for name, tc := range map[string]struct {
+		args   []string
+		code   int
+		stderr string
+	}{
+		"no command":             {args: nil, code: exitUsage, stderr: "Usage: gue <command>"},
+		"help":                   {args: []string{"help"}, code: exitOK, stderr: "Usage: gue <command>"},
+		"unknown command":        {args: []string{"foo"}, code: exitUsage, stderr: `unknown command "foo"`},
+		"unknown flag":           {args: []string{"list", "--foo"}, code: exitUsage, stderr: "flag provided but not defined"},
+		"unexpected args":        {args: []string{"list", "foo"}, code: exitUsage, stderr: "unexpected arguments"},
+		"command help":           {args: []string{"stats", "--help"}, code: exitOK, stderr: "-db-url"},
+		"enqueue no type":        {args: []string{"enqueue"}, code: exitUsage, stderr: "--type is required"},
+		"enqueue invalid args":   {args: []string{"enqueue", "--type", "t", "--args", "{"}, code: exitUsage, stderr: "--args must be valid JSON"},
+		"enqueue invalid run at": {args: []string{"enqueue", "--type", "t", "--run-at", "tomorrow"}, code: exitUsage, stderr: "--run-at"},
+		"enqueue priority":       {args: []string{"enqueue", "--type", "t", "--priority", "40000"}, code: exitUsage, stderr: "--priority must be between"},
+		"list invalid id":        {args: []string{"list", "--id", "123"}, code: exitUsage, stderr: "--id"},
+		"list negative limit":    {args: []string{"list", "--limit", "-1"}, code: exitUsage, stderr: "--limit must not be negative"},
+		"retry no selector":      {args: []string{"retry"}, code: exitUsage, stderr: "either --id or --all-errored is required"},
+		"retry both selectors":   {args: []string{"retry", "--id", "01HQJZQX6Y8N3B1Y5M2T3H8Z9K", "--all-errored"}, code: exitUsage, stderr: "mutually exclusive"},
+		"delete no id":           {args: []string{"delete"}, code: exitUsage, stderr: "--id is required"},
+		"purge no filter":        {args: []string{"purge", "--type", "t"}, code: exitUsage, stderr: "at least one of --errored or --older-than"},
+		"no db url":              {args: []string{"stats"}, code: exitUsage, stderr: "database URL is not set"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			a, stdout, stderr := newTestApp(nil, failConnect)
+
+			code := a.run(context.Background(), tc.args)
+			assert.Equal(t, tc.code, code)
+			assert.Contains(t, stderr.String(), tc.stderr)
+			assert.Empty(t, stdout.String())
+		})
+	}
+}
+
+func TestApp_ConnectFailure(t *testing.T) {
+	var connectedURL string
+	a, _, stderr := newTestApp(
+		map[string]string{"DATABASE_URL": "postgres://fallback", "GUE_DATABASE_URL": "postgres://gue"},
+		func(_ context.Context, dbURL string) (adapter.ConnPool, error) {
+			connectedURL = dbURL
+			return nil, errors.New("connection refused")
+		},
+	)
+
+	code := a.run(context.Background(), []string{"stats"})
+	assert.Equal(t, exitFailure, code)
+	assert.Equal(t, "postgres://gue", connectedURL)
+	assert.Contains(t, stderr.String(), "connection refused")
+
+	code = a.run(context.Background(), []string{"stats", "--db-url", "postgres://flag"})
+	assert.Equal(t, exitFailure, code)
+	assert.Equal(t, "postgres://flag", connectedURL)
+}
+
+func TestApp(t *testing.T) {
+	connPool := adapterTesting.OpenTestPoolPGXv5(t)
+	ctx := context.Background()
+
+	run := func(t *testing.T, args ...string) string {
+		t.Helper()
+
+		a, stdout, stderr := newTestApp(nil, func(context.Context, string) (adapter.ConnPool, error) {
+			return testPool{connPool}, nil
+		})
+
+		code := a.run(ctx, append(args, "--db-url", "postgres://test"))
+		require.Equal(t, exitOK, code, stderr.String())
+		return stdout.String()
+	}
+
+	var enqueued jobView
+	err := json.Unmarshal([]byte(run(t, "enqueue", "--type", "send_email", "--queue", "cli-mailers", "--args", `{"to":"x"}`, "--json")), &enqueued)
+	require.NoError(t, err)
+	assert.Equal(t, "send_email", enqueued.Type)
+	assert.JSONEq(t, `{"to":"x"}`, string(enqueued.Args))
+
+	out := run(t, "enqueue", "--type", "send_sms", "--queue", "cli-mailers", "--run-at", time.Now().Add(time.Hour).Format(time.RFC3339))
+	assert.Len(t, out, len(enqueued.ID)+1)
+
+	var stats []statsView
+	require.NoError(t, json.Unmarshal([]byte(run(t, "stats", "--queue", "cli-mailers", "--json")), &stats))
+	require.Len(t, stats, 1)
+	assert.Equal(t, int64(2), stats[0].Total)
+	assert.Equal(t, int64(1), stats[0].Ready)
+	assert.Equal(t, int64(1), stats[0].Scheduled)
+
+	out = run(t, "list", "--queue", "cli-mailers")
+	assert.Contains(t, out, "LAST ERROR")
+	assert.Contains(t, out, enqueued.ID)
+
+	c, err := gue.NewClient(connPool)
+	require.NoError(t, err)
+	j, err := c.LockJob(ctx, "cli-mailers")
+	require.NoError(t, err)
+	require.NotNil(t, j)
+	require.NoError(t, j.Error(ctx, errors.New("smtp is down")))
+	require.NoError(t, j.Done(ctx))
+
+	var errored []jobView
+	require.NoError(t, json.Unmarshal([]byte(run(t, "list", "--errored", "--json")), &errored))
+	require.Len(t, errored, 1)
+	assert.Equal(t, enqueued.ID, errored[0].ID)
+	assert.Equal(t, "smtp is down", errored[0].LastError)
+
+	assert.Equal(t, "retried 1 job(s)\n", run(t, "retry", "--all-errored", "--type", "send_email"))
+	assert.Equal(t, "deleted 1 job(s)\n", run(t, "delete", "--id", enqueued.ID))
+	assert.Equal(t, "purged 0 job(s)\n", run(t, "purge", "--errored"))
+	assert.Equal(t, "purged 1 job(s)\n", run(t, "purge", "--older-than", "1ns"))
+}
@@ -0,0 +1,351 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/oklog/ulid/v2"
+
+	"github.com/vortex14/gue/v7"
+)
+
+const (
+	timeFormat        = time.RFC3339
+	lastErrorMaxWidth = 60
+)
+
+func runEnqueue(ctx context.Context, a *app, args []string) error {
+	fs, cf := a.flagSet("enqueue")
+	jobType := fs.String("type", "", "job type, required")
+	queue := fs.String("queue", "", "queue name, default queue if not set")
+	jobArgs := fs.String("args", "{}", "job args as JSON")
+	priority := fs.Int("priority", int(gue.JobPriorityDefault), "job priority, lower value means higher priority")
+	runAt := fs.String("run-at", "", "time to run the job at in RFC3339 format, now if not set")
+	if err := a.parse(fs, args); err != nil {
+		return err
+	}
+
+	if *jobType == "" {
+		return fmt.Errorf("%w: --type is required", errUsage)
+	}
+	if !json.Valid([]byte(*jobArgs)) {
+		return fmt.Errorf("%w: --args must be valid JSON", errUsage)
+	}
+	if *priority < int(gue.JobPriorityHighest) || *priority > int(gue.JobPriorityLowest) {
+		return fmt.Errorf("%w: --priority must be between %d and %d", errUsage, gue.JobPriorityHighest, gue.JobPriorityLowest)
+	}
+
+	options := []gue.JobOption{gue.WithJobQueue(*queue), gue.WithJobPriority(gue.JobPriority(*priority))}
+	if *runAt != "" {
+		t, err := time.Parse(timeFormat, *runAt)
+		if err != nil {
+			return fmt.Errorf("%w: --run-at: %v", errUsage, err)
+		}
+		options = append(options, gue.WithJobRunAt(t))
+	}
+
+	j, err := gue.NewJob(*jobType, json.RawMessage(*jobArgs), options...)
+	if err != nil {
+		return err
+	}
+
+	c, closePool, err := a.client(ctx, cf)
+	if err != nil {
+		return err
+	}
+	defer closePool()
+
+	if err := c.Enqueue(ctx, j); err != nil {
+		return fmt.Errorf("could not enqueue job: %w", err)
+	}
+
+	if cf.json {
+		return a.printJSON(newJobView(j))
+	}
+
+	_, err = fmt.Fprintln(a.stdout, j.ID.String())
+	return err
+}
+
+func runStats(ctx context.Context, a *app, args []string) error {
+	fs, cf := a.flagSet("stats")
+	var queues stringsFlag
+	fs.Var(&queues, "queue", "queue name, can be repeated, all queues if not set")
+	if err := a.parse(fs, args); err != nil {
+		return err
+	}
+
+	c, closePool, err := a.client(ctx, cf)
+	if err != nil {
+		return err
+	}
+	defer closePool()
+
+	stats, err := c.QueueStats(ctx, queues...)
+	if err != nil {
+		return err
+	}
+
+	if cf.json {
+		views := make([]statsView, 0, len(stats))
+		for _, s := range stats {
+			views = append(views, newStatsView(s))
+		}
+		return a.printJSON(views)
+	}
+
+	tw := tabwriter.NewWriter(a.stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "QUEUE\tTOTAL\tREADY\tSCHEDULED\tERRORED\tOLDEST READY")
+	for _, s := range stats {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%d\t%s\n", s.Queue, s.Total, s.Ready, s.Scheduled, s.Errored, formatTime(s.OldestReadyRunAt))
+	}
+	return tw.Flush()
+}
+
+func runList(ctx context.Context, a *app, args []string) error {
+	fs, cf := a.flagSet("list")
+	var queues stringsFlag
+	fs.Var(&queues, "queue", "queue name, can be repeated, all queues if not set")
+	id := fs.String("id", "", "job ID")
+	jobType := fs.String("type", "", "job type")
+	errored := fs.Bool("errored", false, "list only jobs that failed at least once")
+	limit := fs.Int("limit", 20, "maximum number of jobs to list, 0 for no limit")
+	if err := a.parse(fs, args); err != nil {
+		return err
+	}
+
+	f := gue.JobFilter{Queues: queues, Type: *jobType, Errored: *errored, Limit: *limit}
+	if err := parseID(*id, &f); err != nil {
+		return err
+	}
+	if *limit < 0 {
+		return fmt.Errorf("%w: --limit must not be negative", errUsage)
+	}
+
+	c, closePool, err := a.client(ctx, cf)
+	if err != nil {
+		return err
+	}
+	defer closePool()
+
+	jobs, err := c.ListJobs(ctx, f)
+	if err != nil {
+		return err
+	}
+
+	views := make([]jobView, 0, len(jobs))
+	for _, j := range jobs {
+		views = append(views, newJobView(j))
+	}
+
+	if cf.json {
+		return a.printJSON(views)
+	}
+
+	tw := tabwriter.NewWriter(a.stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tQUEUE\tTYPE\tPRIORITY\tRUN AT\tERRORS\tLAST ERROR")
+	for _, v := range views {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%s\t%d\t%s\n", v.ID, v.Queue, v.Type, v.Priority, formatTime(v.RunAt), v.ErrorCount, truncate(v.LastError, lastErrorMaxWidth))
+	}
+	return tw.Flush()
+}
+
+func runRetry(ctx context.Context, a *app, args []string) error {
+	fs, cf := a.flagSet("retry")
+	var queues stringsFlag
+	fs.Var(&queues, "queue", "queue name, can be repeated, all queues if not set")
+	id := fs.String("id", "", "job ID")
+	allErrored := fs.Bool("all-errored", false, "retry all the jobs that failed at least once")
+	jobType := fs.String("type", "", "job type, used with --all-errored")
+	if err := a.parse(fs, args); err != nil {
+		return err
+	}
+
+	var f gue.JobFilter
+	switch {
+	case *id != "" && *allErrored:
+		return fmt.Errorf("%w: --id and --all-errored are mutually exclusive", errUsage)
+	case *id != "":
+		if err := parseID(*id, &f); err != nil {
+			return err
+		}
+	case *allErrored:
+		f = gue.JobFilter{Queues: queues, Type: *jobType, Errored: true}
+	default:
+		return fmt.Errorf("%w: either --id or --all-errored is required", errUsage)
+	}
+
+	return a.changeJobs(ctx, cf, f, "retried", func(c *gue.Client) (int64, error) {
+		return c.RetryJobs(ctx, f)
+	})
+}
+
+func runDelete(ctx context.Context, a *app, args []string) error {
+	fs, cf := a.flagSet("delete")
+	id := fs.String("id", "", "job ID, required")
+	if err := a.parse(fs, args); err != nil {
+		return err
+	}
+
+	if *id == "" {
+		return fmt.Errorf("%w: --id is required", errUsage)
+	}
+
+	var f gue.JobFilter
+	if err := parseID(*id, &f); err != nil {
+		return err
+	}
+
+	return a.changeJobs(ctx, cf, f, "deleted", func(c *gue.Client) (int64, error) {
+		return c.DeleteJobs(ctx, f)
+	})
+}
+
+func runPurge(ctx context.Context, a *app, args []string) error {
+	fs, cf := a.flagSet("purge")
+	var queues stringsFlag
+	fs.Var(&queues, "queue", "queue name, can be repeated, all queues if not set")
+	jobType := fs.String("type", "", "job type")
+	errored := fs.Bool("errored", false, "purge only jobs that failed at least once")
+	olderThan := fs.Duration("older-than", 0, "purge only jobs created earlier than the given duration ago, e.g. 720h")
+	if err := a.parse(fs, args); err != nil {
+		return err
+	}
+
+	// purging all the jobs is too dangerous to be the default
+	if !*errored && *olderThan <= 0 {
+		return fmt.Errorf("%w: at least one of --errored or --older-than is required", errUsage)
+	}
+
+	f := gue.JobFilter{Queues: queues, Type: *jobType, Errored: *errored}
+	if *olderThan > 0 {
+		f.CreatedBefore = a.now().Add(-*olderThan)
+	}
+
+	return a.changeJobs(ctx, cf, f, "purged", func(c *gue.Client) (int64, error) {
+		return c.DeleteJobs(ctx, f)
+	})
+}
+
+// changeJobs runs the command that changes the jobs and prints the number of affected jobs. Command selecting
+// the single job by ID fails if the job was not affected, that is the job does not exist or is being worked.
+func (a *app) changeJobs(ctx context.Context, cf *commonFlags, f gue.JobFilter, verb string, change func(c *gue.Client) (int64, error)) error {
+	c, closePool, err := a.client(ctx, cf)
+	if err != nil {
+		return err
+	}
+	defer closePool()
+
+	n, err := change(c)
+	if err != nil {
+		return err
+	}
+
+	if cf.json {
+		if err := a.printJSON(map[string]int64{verb: n}); err != nil {
+			return err
+		}
+	} else {
+		fmt.Fprintf(a.stdout, "%s %d job(s)\n", verb, n)
+	}
+
+	if f.ID != (ulid.ULID{}) && n == 0 {
+		return fmt.Errorf("job %s is not found or is being worked at the moment", f.ID)
+	}
+
+	return nil
+}
+
+func parseID(id string, f *gue.JobFilter) error {
+	if id == "" {
+		return nil
+	}
+
+	parsed, err := ulid.Parse(id)
+	if err != nil {
+		return fmt.Errorf("%w: --id: %v", errUsage, err)
+	}
+
+	f.ID = parsed
+	return nil
+}
+
+type jobView struct {
+	ID         string          `json:"id"`
+	Queue      string          `json:"queue"`
+	Type       string          `json:"type"`
+	Priority   int16           `json:"priority"`
+	RunAt      time.Time       `json:"run_at"`
+	Args       json.RawMessage `json:"args"`
+	ErrorCount int32           `json:"error_count"`
+	LastError  string          `json:"last_error,omitempty"`
+	CreatedAt  time.Time       `json:"created_at"`
+}
+
+func newJobView(j *gue.Job) jobView {
+	v := jobView{
+		ID:         j.ID.String(),
+		Queue:      j.Queue,
+		Type:       j.Type,
+		Priority:   int16(j.Priority),
+		RunAt:      j.RunAt,
+		ErrorCount: j.ErrorCount,
+		LastError:  j.LastError.String,
+		CreatedAt:  j.CreatedAt,
+	}
+
+	// args are not necessarily JSON when the job was enqueued by other means than gue.NewJob
+	if json.Valid(j.Args) {
+		v.Args = j.Args
+	} else if len(j.Args) > 0 {
+		v.Args, _ = json.Marshal(string(j.Args))
+	}
+
+	return v
+}
+
+type statsView struct {
+	Queue            string     `json:"queue"`
+	Total            int64      `json:"total"`
+	Ready            int64      `json:"ready"`
+	Scheduled        int64      `json:"scheduled"`
+	Errored          int64      `json:"errored"`
+	OldestReadyRunAt *time.Time `json:"oldest_ready_run_at"`
+}
+
+func newStatsView(s gue.QueueStats) statsView {
+	v := statsView{
+		Queue:     s.Queue,
+		Total:     s.Total,
+		Ready:     s.Ready,
+		Scheduled: s.Scheduled,
+		Errored:   s.Errored,
+	}
+	if !s.OldestReadyRunAt.IsZero() {
+		v.OldestReadyRunAt = &s.OldestReadyRunAt
+	}
+
+	return v
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+
+	return t.UTC().Format(timeFormat)
+}
+
+func truncate(s string, n int) string {
+	// multiline errors, e.g. panic stack traces, break the table layout
+	s, _, _ = strings.Cut(s, "\n")
+	if r := []rune(s); len(r) > n {
+		return string(r[:n-1]) + "…"
+	}
+
+	return s
+}
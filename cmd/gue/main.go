@@ -0,0 +1,36 @@
+// Command gue is the command line tool to enqueue, inspect and manage gue jobs, e.g. from runbooks and cron.
+//
+// Usage:
+//
+//	gue <command> [flags]
+//
+// Commands:
+//
+//	enqueue  enqueue new job
+//	stats    show queues summary
+//	list     list jobs
+//	retry    schedule jobs to be executed immediately
+//	delete   delete the job
+//	purge    delete jobs in bulk
+//
+// Database URL is taken from the --db-url flag or from the GUE_DATABASE_URL or DATABASE_URL environment variables.
+// Results are printed as human-readable tables, use --json flag to get JSON output for scripting.
+// Command exits with non-zero code on failure.
+//
+// Read-only commands (stats and list) never lock the jobs, so they do not interfere with the workers. Commands that
+// change the jobs skip the ones that are being worked at the moment.
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+func main() {
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	os.Exit(newApp().run(ctx, os.Args[1:]))
+}
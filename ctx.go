@@ -2,10 +2,11 @@ package gue
 
 import "context"
 
-type ctxKey struct{}
+type ctxKey int
 
-var (
-	workerIdxKey = ctxKey{}
+const (
+	workerIdxKey ctxKey = iota
+	workerIDKey
 )
 
 const (
@@ -31,3 +32,22 @@ func GetWorkerIdx(ctx context.Context) int {
 
 	return WorkerIdxUnknown
 }
+
+// setWorkerID sets the ID of the worker to the worker context.
+func setWorkerID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, workerIDKey, id)
+}
+
+// GetWorkerID gets the ID of the worker that works the job from the context passed to the WorkFunc and hooks.
+// Returns empty string if the context is not set or the value is not found there.
+func GetWorkerID(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+
+	if id, ok := ctx.Value(workerIDKey).(string); ok {
+		return id
+	}
+
+	return ""
+}
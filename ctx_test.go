@@ -25,3 +25,22 @@ func TestSetWorkerIdx(t *testing.T) {
 		assert.Equal(t, 99, idx)
 	})
 }
+
+func TestSetWorkerID(t *testing.T) {
+	t.Run("no ctx", func(t *testing.T) {
+		id := GetWorkerID(nil)
+		assert.Empty(t, id)
+	})
+
+	t.Run("no id in the ctx", func(t *testing.T) {
+		ctx := context.Background()
+		id := GetWorkerID(ctx)
+		assert.Empty(t, id)
+	})
+
+	t.Run("id is set", func(t *testing.T) {
+		ctx := setWorkerID(context.Background(), "pool/worker-1")
+		id := GetWorkerID(ctx)
+		assert.Equal(t, "pool/worker-1", id)
+	})
+}
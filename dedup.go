@@ -0,0 +1,59 @@
+package gue
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// defaultDedupDelay is longer than defaultLimitedJobDelay, as the duplicate job waits for the handler of the job
+// with the same key to finish, and not for the limiter token that is usually available soon.
+const defaultDedupDelay = 5 * time.Second
+
+// DedupKeyFunc computes the deduplication key for the job, e.g. "rebuild-cache:user-42". Jobs with the same key
+// are never worked concurrently by the workers sharing the same DedupKeySet. Empty key means that the job
+// is not deduplicated.
+type DedupKeyFunc func(j *Job) string
+
+// DedupKeySet keeps track of the keys of the jobs that are being worked at the moment. DedupKeySet implementations
+// must be safe for concurrent use. Custom implementation, e.g. backed by the shared store, can be used
+// to deduplicate jobs across several application instances.
+type DedupKeySet interface {
+	// Acquire registers the key as being worked and reports whether it was not registered yet. Job with the key
+	// that is already registered is postponed by the worker.
+	Acquire(ctx context.Context, key string) (bool, error)
+	// Release unregisters the key once the job handler finished.
+	Release(ctx context.Context, key string) error
+}
+
+// NewDedupKeySet returns in-memory DedupKeySet that deduplicates jobs within the process.
+func NewDedupKeySet() DedupKeySet {
+	return &dedupKeySet{keys: make(map[string]struct{})}
+}
+
+type dedupKeySet struct {
+	mu   sync.Mutex
+	keys map[string]struct{}
+}
+
+// Acquire implements DedupKeySet.Acquire()
+func (s *dedupKeySet) Acquire(_ context.Context, key string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.keys[key]; ok {
+		return false, nil
+	}
+
+	s.keys[key] = struct{}{}
+	return true, nil
+}
+
+// Release implements DedupKeySet.Release()
+func (s *dedupKeySet) Release(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.keys, key)
+	return nil
+}
@@ -0,0 +1,33 @@
+package gue
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewDedupKeySet(t *testing.T) {
+	ctx := context.Background()
+
+	set := NewDedupKeySet()
+
+	acquired, err := set.Acquire(ctx, "key-1")
+	require.NoError(t, err)
+	assert.True(t, acquired)
+
+	acquired, err = set.Acquire(ctx, "key-1")
+	require.NoError(t, err)
+	assert.False(t, acquired)
+
+	acquired, err = set.Acquire(ctx, "key-2")
+	require.NoError(t, err)
+	assert.True(t, acquired)
+
+	require.NoError(t, set.Release(ctx, "key-1"))
+
+	acquired, err = set.Acquire(ctx, "key-1")
+	require.NoError(t, err)
+	assert.True(t, acquired)
+}
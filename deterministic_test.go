@@ -0,0 +1,105 @@
+package gue_test
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/vortex14/gue/v7"
+	"github.com/vortex14/gue/v7/adapter"
+	adapterTesting "github.com/vortex14/gue/v7/adapter/testing"
+	"github.com/vortex14/gue/v7/guetest"
+)
+
+var goldenDeterministicSequence = []string{
+	"0 start a ok",
+	"1 step a ok",
+	"2 finish a ok",
+	"0 start b ok",
+	"1 step b ok",
+	"2 finish b ok",
+	"0 flaky c error",
+	"0 flaky c ok",
+}
+
+func TestDeterministic(t *testing.T) {
+	for name, openFunc := range adapterTesting.AllAdaptersOpenTestPool {
+		t.Run(name, func(t *testing.T) {
+			// run the same workflow several times to ensure it is reproducible
+			for i := 0; i < 3; i++ {
+				sequence := runDeterministicWorkflow(t, openFunc(t))
+				assert.Equal(t, goldenDeterministicSequence, sequence, "run %d", i)
+			}
+		})
+	}
+}
+
+func runDeterministicWorkflow(t *testing.T, connPool adapter.ConnPool) []string {
+	t.Helper()
+
+	ctx := context.Background()
+	start := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := guetest.NewClock(start)
+
+	c, err := gue.NewClient(connPool, gue.WithClientNowFunc(clock.Now))
+	require.NoError(t, err)
+
+	var (
+		mu       sync.Mutex
+		sequence []string
+	)
+	recordHook := func(ctx context.Context, j *gue.Job, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		outcome := "ok"
+		if err != nil {
+			outcome = "error"
+		}
+		sequence = append(sequence, fmt.Sprintf("%d %s %s %s", gue.GetWorkerIdx(ctx), j.Type, j.Args, outcome))
+	}
+
+	wm := gue.WorkMap{
+		"start": func(ctx context.Context, j *gue.Job) error {
+			return c.Enqueue(ctx, &gue.Job{Type: "step", Args: j.Args})
+		},
+		"step": func(ctx context.Context, j *gue.Job) error {
+			return c.Enqueue(ctx, &gue.Job{Type: "finish", Args: j.Args})
+		},
+		"finish": func(ctx context.Context, j *gue.Job) error {
+			return nil
+		},
+		"flaky": func(ctx context.Context, j *gue.Job) error {
+			if j.ErrorCount == 0 {
+				return gue.ErrRescheduleJobIn(10*time.Second, "not yet")
+			}
+			return nil
+		},
+	}
+
+	pool, err := gue.NewWorkerPool(c, wm, 3, gue.WithPoolHooksJobDone(recordHook))
+	require.NoError(t, err)
+
+	require.NoError(t, c.Enqueue(ctx, &gue.Job{Type: "start", Args: []byte("a"), RunAt: start}))
+	require.NoError(t, c.Enqueue(ctx, &gue.Job{Type: "start", Args: []byte("b"), RunAt: start.Add(time.Second)}))
+	require.NoError(t, c.Enqueue(ctx, &gue.Job{Type: "flaky", Args: []byte("c"), RunAt: start.Add(2 * time.Second)}))
+
+	runner := guetest.Deterministic(pool, guetest.WithClock(clock, time.Second))
+	worked := runner.RunFor(ctx, 15*time.Second)
+	assert.Equal(t, len(goldenDeterministicSequence), worked)
+
+	// nothing should be left in the queue
+	assert.Equal(t, 0, runner.RunUntilIdle(ctx))
+
+	var count int
+	err = connPool.QueryRow(ctx, `SELECT COUNT(1) FROM gue_jobs`).Scan(&count)
+	require.NoError(t, err)
+	require.Zero(t, count, "all the jobs are expected to be worked")
+
+	return sequence
+}
@@ -1,7 +1,9 @@
 package gue
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"testing"
 	"time"
 
@@ -264,3 +266,78 @@ func testEnqueueBatchTx(t *testing.T, connPool adapter.ConnPool) {
 	j = findOneJob(t, connPool)
 	require.Nil(t, j)
 }
+
+func TestEnqueueSplitterErrors(t *testing.T) {
+	ctx := context.Background()
+
+	errSplit := errors.New("could not split")
+	c, err := NewClient(
+		nil,
+		WithClientSplitter("FailingJob", func([]byte) ([][]byte, error) { return nil, errSplit }, 1),
+		WithClientSplitter("EmptyJob", func([]byte) ([][]byte, error) { return nil, nil }, 1),
+	)
+	require.NoError(t, err)
+
+	// splitter errors are returned before the DB is touched
+	err = c.execEnqueue(ctx, &Job{Type: "FailingJob", Args: []byte("123")}, nil)
+	assert.ErrorIs(t, err, errSplit)
+
+	err = c.execEnqueue(ctx, &Job{Type: "EmptyJob", Args: []byte("123")}, nil)
+	assert.Error(t, err)
+}
+
+func TestEnqueueSplitter(t *testing.T) {
+	for name, openFunc := range adapterTesting.AllAdaptersOpenTestPool {
+		t.Run(name, func(t *testing.T) {
+			testEnqueueSplitter(t, openFunc(t))
+		})
+	}
+}
+
+func testEnqueueSplitter(t *testing.T, connPool adapter.ConnPool) {
+	ctx := context.Background()
+
+	c, err := NewClient(connPool, WithClientSplitter("MyJob", func(args []byte) ([][]byte, error) {
+		return bytes.Split(args, []byte(",")), nil
+	}, 5))
+	require.NoError(t, err)
+
+	// args within the threshold are not split
+	small := Job{Type: "MyJob", Args: []byte("1,2")}
+	err = c.Enqueue(ctx, &small)
+	require.NoError(t, err)
+	require.NotEmpty(t, small.ID)
+
+	oversized := Job{Type: "MyJob", Queue: "split", Priority: JobPriorityHigh, Args: []byte("1,2,3,4")}
+	err = c.Enqueue(ctx, &oversized)
+	require.NoError(t, err)
+	// original job is never inserted
+	assert.Empty(t, oversized.ID)
+
+	var args []string
+	for {
+		j, err := c.LockJob(ctx, "split")
+		require.NoError(t, err)
+		if j == nil {
+			break
+		}
+
+		assert.Equal(t, "MyJob", j.Type)
+		assert.Equal(t, JobPriorityHigh, j.Priority)
+		args = append(args, string(j.Args))
+
+		t.Cleanup(func() {
+			err := j.Done(ctx)
+			assert.NoError(t, err)
+		})
+	}
+	assert.ElementsMatch(t, []string{"1", "2", "3", "4"}, args)
+
+	// split jobs are added atomically with the rest of the batch
+	err = c.EnqueueBatch(ctx, []*Job{{Type: "MyJob", Queue: "split-failed", Args: []byte("5,6,7,8")}, {}})
+	require.ErrorIs(t, err, ErrMissingType)
+
+	j, err := c.LockJob(ctx, "split-failed")
+	require.NoError(t, err)
+	assert.Nil(t, j)
+}
@@ -4,6 +4,8 @@ import (
 	"errors"
 	"fmt"
 	"time"
+
+	"github.com/vortex14/gue/v7/adapter"
 )
 
 var (
@@ -16,11 +18,19 @@ var (
 	// Error is normally returned wrapped, so use `errors.Is(err, gue.ErrHookJobDonePanicked)` to ensure this is the error you're
 	// looking for.
 	ErrHookJobDonePanicked = errors.New("hook job done panicked in job panic recovery")
+
+	// ErrJobNotLocked is returned by the Job methods that change the job state when the job lock was already released
+	// with Done(), e.g. when the job is used by the goroutine spawned in the handler after the handler returned,
+	// or when the job was not locked at all. It wraps adapter.ErrTxClosed, as the job lock is the transaction.
+	ErrJobNotLocked = fmt.Errorf("job is not locked: %w", adapter.ErrTxClosed)
+
+	// ErrWorkerPoolStopped is returned when the worker pool that was already shut down is being run again.
+	ErrWorkerPoolStopped = errors.New("worker pool is stopped")
 )
 
 // ErrJobReschedule interface implementation allows errors to reschedule jobs in the individual basis.
 type ErrJobReschedule interface {
-	rescheduleJobAt() time.Time
+	rescheduleJobAt(now time.Time) time.Time
 }
 
 type errJobRescheduleIn struct {
@@ -38,8 +48,8 @@ func (e errJobRescheduleIn) Error() string {
 	return fmt.Sprintf("rescheduling job in %q because %q", e.d.String(), e.s)
 }
 
-func (e errJobRescheduleIn) rescheduleJobAt() time.Time {
-	return time.Now().Add(e.d)
+func (e errJobRescheduleIn) rescheduleJobAt(now time.Time) time.Time {
+	return now.Add(e.d)
 }
 
 type errJobRescheduleAt struct {
@@ -57,7 +67,7 @@ func (e errJobRescheduleAt) Error() string {
 	return fmt.Sprintf("rescheduling job at %q because %q", e.t.String(), e.s)
 }
 
-func (e errJobRescheduleAt) rescheduleJobAt() time.Time {
+func (e errJobRescheduleAt) rescheduleJobAt(time.Time) time.Time {
 	return e.t
 }
 
@@ -75,6 +85,43 @@ func (e errJobDiscard) Error() string {
 	return fmt.Sprintf("discarding job because %q", e.s)
 }
 
-func (e errJobDiscard) rescheduleJobAt() time.Time {
+func (e errJobDiscard) rescheduleJobAt(time.Time) time.Time {
 	return time.Time{}
 }
+
+type errJobPostpone struct {
+	t time.Time
+	d time.Duration
+	s string
+}
+
+// ErrPostponeJobIn spawns an error that postpones a job to run after some predefined duration.
+// Unlike ErrRescheduleJobIn, postponed job is not considered as failed - Worker neither increases its error count
+// nor stores the error message, but only changes the time the job will run at.
+// Postpone error takes precedence over the generic error handling even if it is wrapped.
+func ErrPostponeJobIn(d time.Duration, reason string) error {
+	return errJobPostpone{d: d, s: reason}
+}
+
+// ErrPostponeJobAt spawns an error that postpones a job to run at some predefined time.
+// See ErrPostponeJobIn for details on how postponed job differs from rescheduled one.
+func ErrPostponeJobAt(t time.Time, reason string) error {
+	return errJobPostpone{t: t, s: reason}
+}
+
+// Error implements error.Error()
+func (e errJobPostpone) Error() string {
+	if e.t.IsZero() {
+		return fmt.Sprintf("postponing job in %q because %q", e.d.String(), e.s)
+	}
+
+	return fmt.Sprintf("postponing job at %q because %q", e.t.String(), e.s)
+}
+
+func (e errJobPostpone) postponeJobAt(now time.Time) time.Time {
+	if e.t.IsZero() {
+		return now.Add(e.d)
+	}
+
+	return e.t
+}
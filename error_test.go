@@ -138,3 +138,88 @@ func testErrDiscardJob(t *testing.T, connPool adapter.ConnPool) {
 	require.Error(t, err)
 	assert.Nil(t, jLocked2)
 }
+
+func TestErrPostponeJob_Error(t *testing.T) {
+	errPostponeIn := ErrPostponeJobIn(10*time.Second, "not ready yet")
+	assert.Equal(t, `postponing job in "10s" because "not ready yet"`, errPostponeIn.Error())
+
+	postponeAt := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	errPostponeAt := ErrPostponeJobAt(postponeAt, "not ready yet")
+	assert.Equal(t, fmt.Sprintf(`postponing job at "%s" because "not ready yet"`, postponeAt.String()), errPostponeAt.Error())
+
+	now := time.Now()
+	assert.Equal(t, now.Add(10*time.Second), errPostponeIn.(errJobPostpone).postponeJobAt(now))
+	assert.Equal(t, postponeAt, errPostponeAt.(errJobPostpone).postponeJobAt(now))
+
+	// postpone errors must not be treated as regular reschedule ones
+	_, ok := errPostponeIn.(ErrJobReschedule)
+	assert.False(t, ok)
+}
+
+func TestErrPostponeJob(t *testing.T) {
+	for name, openFunc := range adapterTesting.AllAdaptersOpenTestPool {
+		t.Run(name, func(t *testing.T) {
+			testErrPostponeJob(t, openFunc(t))
+		})
+	}
+}
+
+func testErrPostponeJob(t *testing.T, connPool adapter.ConnPool) {
+	t.Helper()
+
+	ctx := context.Background()
+	postponeAt := time.Now().Add(3 * time.Hour)
+
+	c, err := NewClient(connPool)
+	require.NoError(t, err)
+
+	wm := WorkMap{
+		"postpone-in": func(ctx context.Context, j *Job) error {
+			return ErrPostponeJobIn(time.Hour, "not ready yet")
+		},
+		"postpone-at-wrapped": func(ctx context.Context, j *Job) error {
+			return fmt.Errorf("wrapped: %w", ErrPostponeJobAt(postponeAt, "not ready yet"))
+		},
+	}
+
+	jobDoneHook := new(mockHook)
+	w, err := NewWorker(c, wm, WithWorkerHooksJobDone(jobDoneHook.handler))
+	require.NoError(t, err)
+
+	jIn := Job{Type: "postpone-in"}
+	err = c.Enqueue(ctx, &jIn)
+	require.NoError(t, err)
+
+	jAt := Job{Type: "postpone-at-wrapped", RunAt: time.Now().Add(-time.Minute)}
+	err = c.Enqueue(ctx, &jAt)
+	require.NoError(t, err)
+
+	require.True(t, w.WorkOne(ctx))
+	require.True(t, w.WorkOne(ctx))
+	require.False(t, w.WorkOne(ctx))
+
+	assert.Equal(t, 2, jobDoneHook.called)
+	assert.Error(t, jobDoneHook.err)
+
+	jLockedIn, err := c.LockJobByID(ctx, jIn.ID)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		err := jLockedIn.Done(ctx)
+		assert.NoError(t, err)
+	})
+
+	assert.Equal(t, int32(0), jLockedIn.ErrorCount)
+	assert.False(t, jLockedIn.LastError.Valid)
+	assert.GreaterOrEqual(t, jLockedIn.RunAt.Sub(jIn.RunAt), time.Hour)
+
+	jLockedAt, err := c.LockJobByID(ctx, jAt.ID)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		err := jLockedAt.Done(ctx)
+		assert.NoError(t, err)
+	})
+
+	assert.Equal(t, int32(0), jLockedAt.ErrorCount)
+	assert.False(t, jLockedAt.LastError.Valid)
+	assert.True(t, jLockedAt.RunAt.Round(time.Second).Equal(postponeAt.Round(time.Second)))
+}
@@ -0,0 +1,39 @@
+// Package guehealth provides the HTTP health check handler for the gue workers.
+package guehealth
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/vortex14/gue/v7"
+)
+
+// Checker reports the WorkerPool health, gue.WorkerPool implements it.
+type Checker interface {
+	Health() gue.WorkerPoolHealth
+}
+
+var _ Checker = (*gue.WorkerPool)(nil)
+
+// NewHandler builds http.Handler that responds with the pool health serialized as JSON, so it can be mounted
+// as readiness or liveness probe directly, e.g. at /healthz. Response status is 200 OK for the healthy pool
+// and 503 Service Unavailable otherwise.
+func NewHandler(c Checker) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h := c.Health()
+
+		status := http.StatusOK
+		if !h.Healthy {
+			status = http.StatusServiceUnavailable
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(status)
+
+		if r.Method == http.MethodHead {
+			return
+		}
+		_ = json.NewEncoder(w).Encode(h)
+	})
+}
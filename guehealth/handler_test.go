@@ -0,0 +1,76 @@
+package guehealth
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/vortex14/gue/v7"
+)
+
+type checkerFunc func() gue.WorkerPoolHealth
+
+func (f checkerFunc) Health() gue.WorkerPoolHealth {
+	return f()
+}
+
+func TestNewHandler(t *testing.T) {
+	for name, tc := range map[string]struct {
+		health gue.WorkerPoolHealth
+		status int
+	}{
+		"healthy": {
+			health: gue.WorkerPoolHealth{ID: "pool", Status: gue.WorkerStatusRunning, Healthy: true, Workers: []gue.WorkerHealth{
+				{ID: "pool/worker-0", Status: gue.WorkerStatusRunning, Healthy: true},
+			}},
+			status: http.StatusOK,
+		},
+		"not healthy": {
+			health: gue.WorkerPoolHealth{ID: "pool", Status: gue.WorkerStatusRunning, Workers: []gue.WorkerHealth{
+				{ID: "pool/worker-0", Status: gue.WorkerStatusRunning, Reason: "job is being worked for too long"},
+			}},
+			status: http.StatusServiceUnavailable,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			h := NewHandler(checkerFunc(func() gue.WorkerPoolHealth { return tc.health }))
+
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", http.NoBody))
+
+			assert.Equal(t, tc.status, rec.Code)
+			assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+
+			var got gue.WorkerPoolHealth
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &got))
+			assert.Equal(t, tc.health, got)
+
+			rec = httptest.NewRecorder()
+			h.ServeHTTP(rec, httptest.NewRequest(http.MethodHead, "/healthz", http.NoBody))
+			assert.Equal(t, tc.status, rec.Code)
+			assert.Empty(t, rec.Body.Bytes())
+		})
+	}
+}
+
+func TestNewHandler_WorkerPool(t *testing.T) {
+	c, err := gue.NewClient(nil)
+	require.NoError(t, err)
+
+	p, err := gue.NewWorkerPool(c, gue.WorkMap{}, 2)
+	require.NoError(t, err)
+
+	// pool that is not running is not healthy
+	rec := httptest.NewRecorder()
+	NewHandler(p).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", http.NoBody))
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+
+	var got gue.WorkerPoolHealth
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &got))
+	assert.Equal(t, gue.WorkerStatusStopped, got.Status)
+	assert.Len(t, got.Workers, 2)
+}
@@ -0,0 +1,34 @@
+package guetest
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock is the fake clock that moves forward only when it is explicitly told to. Use Clock.Now as the Client
+// time source with gue.WithClientNowFunc to get reproducible job scheduling in tests.
+type Clock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+// NewClock instantiates new Clock set to the given time.
+func NewClock(start time.Time) *Clock {
+	return &Clock{now: start}
+}
+
+// Now returns current Clock time.
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.now
+}
+
+// Advance moves Clock forward for the given duration.
+func (c *Clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = c.now.Add(d)
+}
@@ -0,0 +1,21 @@
+package guetest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClock(t *testing.T) {
+	start := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := NewClock(start)
+	assert.Equal(t, start, clock.Now())
+	assert.Equal(t, start, clock.Now())
+
+	clock.Advance(time.Minute)
+	assert.Equal(t, start.Add(time.Minute), clock.Now())
+
+	clock.Advance(0)
+	assert.Equal(t, start.Add(time.Minute), clock.Now())
+}
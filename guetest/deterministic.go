@@ -0,0 +1,80 @@
+// Package guetest provides helpers for testing code built on top of gue.
+package guetest
+
+import (
+	"context"
+	"time"
+
+	"github.com/vortex14/gue/v7"
+)
+
+// Runner drives all the workers of a gue.WorkerPool in a single goroutine using fixed round-robin schedule,
+// so the same set of enqueued jobs is always worked in the same order by the same workers.
+type Runner struct {
+	pool  *gue.WorkerPool
+	clock *Clock
+	tick  time.Duration
+}
+
+// Option defines a type that allows to set Runner properties during the build-time.
+type Option func(*Runner)
+
+// WithClock sets the fake clock that Runner advances by tick every time all the workers are idle.
+// The same clock should be used as the Client time source, see gue.WithClientNowFunc.
+func WithClock(clock *Clock, tick time.Duration) Option {
+	return func(r *Runner) {
+		r.clock = clock
+		r.tick = tick
+	}
+}
+
+// Deterministic instantiates new Runner for the pool. The pool must not be started with gue.WorkerPool.Run,
+// Runner is the only thing that should drive it.
+func Deterministic(pool *gue.WorkerPool, options ...Option) *Runner {
+	r := Runner{pool: pool}
+
+	for _, option := range options {
+		option(&r)
+	}
+
+	return &r
+}
+
+// Round gives every worker in the pool exactly one chance to work a job, in the order of the workers indexes.
+// Returns the number of workers that worked a job.
+func (r *Runner) Round(ctx context.Context) int {
+	return r.pool.Step(ctx)
+}
+
+// RunUntilIdle runs rounds until there is a round where no worker worked a job or the context is cancelled.
+// Returns the total number of worked jobs.
+func (r *Runner) RunUntilIdle(ctx context.Context) (worked int) {
+	for ctx.Err() == nil {
+		n := r.Round(ctx)
+		if n == 0 {
+			return worked
+		}
+
+		worked += n
+	}
+
+	return worked
+}
+
+// RunFor runs the pool until it becomes idle, then advances the clock by tick and repeats until the clock
+// moves forward for d, so jobs scheduled within that period are worked as well. When Runner has no clock set
+// RunFor is the same as RunUntilIdle. Returns the total number of worked jobs.
+func (r *Runner) RunFor(ctx context.Context, d time.Duration) (worked int) {
+	worked = r.RunUntilIdle(ctx)
+	if r.clock == nil || r.tick <= 0 {
+		return worked
+	}
+
+	until := r.clock.Now().Add(d)
+	for ctx.Err() == nil && r.clock.Now().Before(until) {
+		r.clock.Advance(r.tick)
+		worked += r.RunUntilIdle(ctx)
+	}
+
+	return worked
+}
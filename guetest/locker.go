@@ -0,0 +1,169 @@
+package guetest
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/vortex14/gue/v7"
+	"github.com/vortex14/gue/v7/adapter"
+)
+
+// Locker is the fake gue.JobLocker that locks canned jobs without the DB, so the worker and handlers can be tested
+// in isolation with gue.WithWorkerJobLocker. Every locked job is bound to the fake transaction that records
+// what happened to the job, use Done, Deleted and Errored to check it.
+type Locker struct {
+	c *gue.Client
+
+	mu     sync.Mutex
+	queued []*gue.Job
+	txs    map[*gue.Job]*lockerTx
+}
+
+// NewLocker instantiates new Locker with the jobs to be locked. Jobs are locked in the given order from the queues
+// set in gue.Job.Queue. Client is used only to bind the jobs to the fake transactions, so it may be created
+// without the DB connection pool, e.g. gue.NewClient(nil).
+func NewLocker(c *gue.Client, jobs ...*gue.Job) *Locker {
+	return &Locker{c: c, queued: jobs, txs: make(map[*gue.Job]*lockerTx)}
+}
+
+// LockJob implements gue.JobLocker. It returns the first not yet locked job from the queue or nil if there are
+// no jobs left in the queue.
+func (l *Locker) LockJob(_ context.Context, queue string) (*gue.Job, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for i, j := range l.queued {
+		if j.Queue != queue {
+			continue
+		}
+
+		l.queued = append(l.queued[:i], l.queued[i+1:]...)
+
+		tx := new(lockerTx)
+		l.txs[j] = tx
+		l.c.BindJobTx(j, tx)
+
+		return j, nil
+	}
+
+	return nil, nil
+}
+
+// Done checks if the job was locked and marked as done.
+func (l *Locker) Done(j *gue.Job) bool {
+	return l.tx(j).isCommitted()
+}
+
+// Deleted checks if the job was locked and deleted, that is the job was worked successfully or discarded.
+func (l *Locker) Deleted(j *gue.Job) bool {
+	return l.tx(j).executed("DELETE FROM gue_jobs")
+}
+
+// Errored checks if the job was locked and marked as failed with gue.Job.Error.
+func (l *Locker) Errored(j *gue.Job) bool {
+	return l.tx(j).executed("UPDATE gue_jobs SET error_count")
+}
+
+func (l *Locker) tx(j *gue.Job) *lockerTx {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if tx, ok := l.txs[j]; ok {
+		return tx
+	}
+
+	// job was never locked, so nothing happened to it
+	return new(lockerTx)
+}
+
+// lockerTx is the fake adapter.Tx that records executed queries.
+type lockerTx struct {
+	mu        sync.Mutex
+	queries   []string
+	committed bool
+}
+
+// Exec implements adapter.Queryable.Exec() by recording the query.
+func (tx *lockerTx) Exec(_ context.Context, query string, _ ...any) (adapter.CommandTag, error) {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+
+	tx.queries = append(tx.queries, query)
+	return lockerCommandTag{}, nil
+}
+
+// QueryRow implements adapter.Queryable.QueryRow(). Locker does not store any data, so there are never rows.
+func (tx *lockerTx) QueryRow(context.Context, string, ...any) adapter.Row {
+	return lockerRow{}
+}
+
+// Query implements adapter.Queryable.Query(). Locker does not store any data, so there are never rows.
+func (tx *lockerTx) Query(context.Context, string, ...any) (adapter.Rows, error) {
+	return lockerRows{}, nil
+}
+
+// Rollback implements adapter.Tx.Rollback().
+func (tx *lockerTx) Rollback(context.Context) error {
+	return nil
+}
+
+// Commit implements adapter.Tx.Commit() by recording the transaction as committed.
+func (tx *lockerTx) Commit(context.Context) error {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+
+	tx.committed = true
+	return nil
+}
+
+func (tx *lockerTx) isCommitted() bool {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+
+	return tx.committed
+}
+
+func (tx *lockerTx) executed(queryPrefix string) bool {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+
+	for _, q := range tx.queries {
+		if strings.HasPrefix(q, queryPrefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+type lockerCommandTag struct{}
+
+// RowsAffected implements adapter.CommandTag.RowsAffected().
+func (lockerCommandTag) RowsAffected() int64 {
+	return 1
+}
+
+type lockerRow struct{}
+
+// Scan implements adapter.Row.Scan().
+func (lockerRow) Scan(...any) error {
+	return adapter.ErrNoRows
+}
+
+type lockerRows struct{}
+
+// Next implements adapter.Rows.Next().
+func (lockerRows) Next() bool {
+	return false
+}
+
+// Scan implements adapter.Rows.Scan().
+func (lockerRows) Scan(...any) error {
+	return adapter.ErrNoRows
+}
+
+// Err implements adapter.Rows.Err().
+func (lockerRows) Err() error {
+	return nil
+}
@@ -0,0 +1,62 @@
+package guetest
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/vortex14/gue/v7"
+)
+
+func TestLocker(t *testing.T) {
+	ctx := context.Background()
+
+	c, err := gue.NewClient(nil)
+	require.NoError(t, err)
+
+	succeeded := &gue.Job{Type: "MyJob", Args: []byte("ok")}
+	errored := &gue.Job{Type: "MyJob", Args: []byte("fail")}
+	discarded := &gue.Job{Type: "MyJob", Args: []byte("discard")}
+	otherQueue := &gue.Job{Type: "MyJob", Queue: "other"}
+
+	locker := NewLocker(c, succeeded, otherQueue, errored, discarded)
+
+	var worked []string
+	wm := gue.WorkMap{"MyJob": func(ctx context.Context, j *gue.Job) error {
+		worked = append(worked, string(j.Args))
+		switch string(j.Args) {
+		case "fail":
+			return errors.New("handler failed")
+		case "discard":
+			return gue.ErrDiscardJob("handler gave up")
+		}
+		return nil
+	}}
+
+	w, err := gue.NewWorker(c, wm, gue.WithWorkerJobLocker(locker))
+	require.NoError(t, err)
+
+	for w.WorkOne(ctx) {
+	}
+	assert.Equal(t, []string{"ok", "fail", "discard"}, worked)
+
+	assert.True(t, locker.Done(succeeded))
+	assert.True(t, locker.Deleted(succeeded))
+	assert.False(t, locker.Errored(succeeded))
+
+	assert.True(t, locker.Done(errored))
+	assert.False(t, locker.Deleted(errored))
+	assert.True(t, locker.Errored(errored))
+
+	assert.True(t, locker.Done(discarded))
+	assert.True(t, locker.Deleted(discarded))
+	assert.False(t, locker.Errored(discarded))
+
+	// job from the other queue is never locked
+	assert.False(t, locker.Done(otherQueue))
+	assert.False(t, locker.Deleted(otherQueue))
+	assert.False(t, locker.Errored(otherQueue))
+}
@@ -0,0 +1,140 @@
+package gue
+
+import (
+	"fmt"
+	"time"
+)
+
+// WorkerHealth is the Worker health snapshot returned by Worker.Health.
+type WorkerHealth struct {
+	ID     string       `json:"id"`
+	Queue  string       `json:"queue"`
+	Status WorkerStatus `json:"status"`
+	// Healthy is false when the worker is stopped or stalled, see WithWorkerStallThreshold.
+	Healthy bool `json:"healthy"`
+	// Reason explains why the worker is not healthy, empty for the healthy worker.
+	Reason string `json:"reason,omitempty"`
+
+	// LastLockAt is the time of the last successful attempt to lock a job, regardless of whether the job was found.
+	LastLockAt time.Time `json:"last_lock_at"`
+	// LastLockError is the error of the last failed attempt to lock a job, it is kept after the lock succeeds again.
+	LastLockError string `json:"last_lock_error,omitempty"`
+	// LockFailingSince is the time of the first failed attempt to lock a job in a row, zero if the last attempt
+	// succeeded.
+	LockFailingSince time.Time `json:"lock_failing_since"`
+
+	// Working is true while the worker is working a job.
+	Working bool `json:"working"`
+	// JobID and JobType describe the job being worked, empty if the worker is not working a job.
+	JobID   string `json:"job_id,omitempty"`
+	JobType string `json:"job_type,omitempty"`
+	// JobDuration is the time the job is being worked for, zero if the worker is not working a job.
+	JobDuration time.Duration `json:"job_duration"`
+}
+
+// WorkerPoolHealth is the WorkerPool health snapshot returned by WorkerPool.Health.
+type WorkerPoolHealth struct {
+	ID     string       `json:"id"`
+	Status WorkerStatus `json:"status"`
+	// Healthy is false when the pool is stopped or any of its workers is not healthy.
+	Healthy bool           `json:"healthy"`
+	Workers []WorkerHealth `json:"workers"`
+}
+
+// workerHealth is the state the Worker updates while working, guarded by Worker.healthMu.
+type workerHealth struct {
+	lastLockAt       time.Time
+	lastLockErr      error
+	lockFailingSince time.Time
+
+	job          *Job
+	jobStartedAt time.Time
+}
+
+// recordLockAttempt updates the worker health with the result of the attempt to lock a job.
+func (w *Worker) recordLockAttempt(err error) {
+	w.healthMu.Lock()
+	defer w.healthMu.Unlock()
+
+	now := time.Now()
+	if err != nil {
+		w.health.lastLockErr = err
+		if w.health.lockFailingSince.IsZero() {
+			w.health.lockFailingSince = now
+		}
+		return
+	}
+
+	w.health.lastLockAt = now
+	w.health.lockFailingSince = time.Time{}
+}
+
+// recordJob updates the worker health with the job being worked, nil job means that the worker finished it.
+func (w *Worker) recordJob(j *Job, startedAt time.Time) {
+	w.healthMu.Lock()
+	defer w.healthMu.Unlock()
+
+	w.health.job, w.health.jobStartedAt = j, startedAt
+}
+
+// Health returns the Worker health snapshot. Health is safe to be called concurrently with the running Worker.
+func (w *Worker) Health() WorkerHealth {
+	status := w.Status()
+
+	w.healthMu.Lock()
+	defer w.healthMu.Unlock()
+
+	now := time.Now()
+	h := WorkerHealth{
+		ID:               w.id,
+		Queue:            w.queue,
+		Status:           status,
+		Healthy:          true,
+		LastLockAt:       w.health.lastLockAt,
+		LockFailingSince: w.health.lockFailingSince,
+	}
+	if w.health.lastLockErr != nil {
+		h.LastLockError = w.health.lastLockErr.Error()
+	}
+	if j := w.health.job; j != nil {
+		h.Working, h.JobID, h.JobType = true, j.ID.String(), j.Type
+		h.JobDuration = now.Sub(w.health.jobStartedAt)
+	}
+
+	switch {
+	case status == WorkerStatusStopped:
+		h.Healthy, h.Reason = false, "worker is stopped"
+	case w.stallThreshold <= 0:
+	case h.Working && h.JobDuration > w.stallThreshold:
+		h.Healthy = false
+		h.Reason = fmt.Sprintf("job is being worked for %s, longer than stall threshold %s", h.JobDuration, w.stallThreshold)
+	case !h.LockFailingSince.IsZero() && now.Sub(h.LockFailingSince) > w.stallThreshold:
+		h.Healthy = false
+		h.Reason = fmt.Sprintf("failed to lock a job for %s, longer than stall threshold %s", now.Sub(h.LockFailingSince), w.stallThreshold)
+	}
+
+	return h
+}
+
+// Health returns the WorkerPool health snapshot including the health of all its Workers. Health is safe
+// to be called concurrently with the running WorkerPool.
+func (w *WorkerPool) Health() WorkerPoolHealth {
+	w.mu.Lock()
+	status := workerStatus(w.running, w.paused)
+	workers := w.workers
+	w.mu.Unlock()
+
+	h := WorkerPoolHealth{
+		ID:      w.id,
+		Status:  status,
+		Healthy: status != WorkerStatusStopped,
+		Workers: make([]WorkerHealth, 0, len(workers)),
+	}
+	for _, worker := range workers {
+		wh := worker.Health()
+		h.Healthy = h.Healthy && wh.Healthy
+		h.Workers = append(h.Workers, wh)
+	}
+
+	return h
+}
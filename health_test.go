@@ -0,0 +1,247 @@
+package gue
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sync/errgroup"
+)
+
+// jobLockerFunc adapts pollFunc to JobLocker, e.g. to set the poll func for the pool workers.
+type jobLockerFunc pollFunc
+
+func (f jobLockerFunc) LockJob(ctx context.Context, queue string) (*Job, error) {
+	return f(ctx, queue)
+}
+
+// pollHealth calls health func in the background until the returned func is called, so the race detector
+// can catch unsafe access to the health state while the worker is running.
+func pollHealth(health func()) (stop func()) {
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-done:
+				return
+			default:
+				health()
+				time.Sleep(100 * time.Microsecond)
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		wg.Wait()
+	}
+}
+
+func TestWorker_HealthStalledJob(t *testing.T) {
+	c, err := NewClient(nil)
+	require.NoError(t, err)
+
+	started, release := make(chan struct{}), make(chan struct{})
+	wm := WorkMap{
+		"MyJob": func(ctx context.Context, j *Job) error {
+			close(started)
+			<-release
+			return nil
+		},
+	}
+
+	w, err := NewWorker(
+		c, wm,
+		WithWorkerID("stalled"),
+		WithWorkerQueue("health"),
+		WithWorkerPollInterval(time.Millisecond),
+		WithWorkerStallThreshold(50*time.Millisecond),
+		withWorkerPollFunc(idlePollFunc(t, c, true)),
+	)
+	require.NoError(t, err)
+
+	h := w.Health()
+	assert.False(t, h.Healthy)
+	assert.Equal(t, WorkerStatusStopped, h.Status)
+	assert.Equal(t, "worker is stopped", h.Reason)
+	assert.Equal(t, "stalled", h.ID)
+	assert.Equal(t, "health", h.Queue)
+
+	stopPolling := pollHealth(func() { w.Health() })
+	defer stopPolling()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var grp errgroup.Group
+	grp.Go(func() error {
+		return w.Run(ctx)
+	})
+	<-started
+
+	h = w.Health()
+	assert.Equal(t, WorkerStatusRunning, h.Status)
+	assert.True(t, h.Working)
+	assert.Equal(t, "MyJob", h.JobType)
+	assert.False(t, h.LastLockAt.IsZero())
+
+	require.Eventually(t, func() bool {
+		return !w.Health().Healthy
+	}, 5*time.Second, time.Millisecond)
+	h = w.Health()
+	assert.Equal(t, WorkerStatusRunning, h.Status)
+	assert.GreaterOrEqual(t, h.JobDuration, 50*time.Millisecond)
+	assert.Contains(t, h.Reason, "job is being worked for")
+
+	close(release)
+	require.Eventually(t, func() bool {
+		return !w.Health().Working
+	}, 5*time.Second, time.Millisecond)
+	h = w.Health()
+	assert.True(t, h.Healthy, h.Reason)
+	assert.Empty(t, h.JobType)
+	assert.Zero(t, h.JobDuration)
+
+	w.Pause()
+	h = w.Health()
+	assert.True(t, h.Healthy, h.Reason)
+	assert.Equal(t, WorkerStatusPaused, h.Status)
+
+	cancel()
+	require.NoError(t, grp.Wait())
+	assert.False(t, w.Health().Healthy)
+}
+
+func TestWorker_HealthLockFailing(t *testing.T) {
+	var failing atomic.Bool
+	failing.Store(true)
+
+	w, err := NewWorker(
+		nil, dummyWM,
+		WithWorkerPollInterval(time.Millisecond),
+		WithWorkerStallThreshold(50*time.Millisecond),
+		withWorkerPollFunc(func(context.Context, string) (*Job, error) {
+			if failing.Load() {
+				return nil, errors.New("db is down")
+			}
+			return nil, nil
+		}),
+	)
+	require.NoError(t, err)
+
+	stopPolling := pollHealth(func() { w.Health() })
+	defer stopPolling()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var grp errgroup.Group
+	grp.Go(func() error {
+		return w.Run(ctx)
+	})
+	require.Eventually(t, w.Running, 5*time.Second, time.Millisecond)
+
+	require.Eventually(t, func() bool {
+		return !w.Health().Healthy
+	}, 5*time.Second, time.Millisecond)
+	h := w.Health()
+	assert.Contains(t, h.Reason, "failed to lock a job for")
+	assert.Equal(t, "db is down", h.LastLockError)
+	assert.False(t, h.LockFailingSince.IsZero())
+	assert.True(t, h.LastLockAt.IsZero())
+
+	failing.Store(false)
+	require.Eventually(t, func() bool {
+		return w.Health().Healthy
+	}, 5*time.Second, time.Millisecond)
+	h = w.Health()
+	assert.True(t, h.LockFailingSince.IsZero())
+	assert.False(t, h.LastLockAt.IsZero())
+	// last error is kept for the diagnostics
+	assert.Equal(t, "db is down", h.LastLockError)
+
+	cancel()
+	require.NoError(t, grp.Wait())
+}
+
+func TestWorkerPool_Health(t *testing.T) {
+	c, err := NewClient(nil)
+	require.NoError(t, err)
+
+	var started sync.WaitGroup
+	started.Add(1)
+	release := make(chan struct{})
+	wm := WorkMap{
+		"MyJob": func(ctx context.Context, j *Job) error {
+			started.Done()
+			<-release
+			return nil
+		},
+	}
+
+	// job is available only once all the workers are running and healthy
+	var jobAvailable atomic.Bool
+	poll := idlePollFunc(t, c, true)
+	p, err := NewWorkerPool(
+		c, wm, 3,
+		WithPoolPollInterval(time.Millisecond),
+		WithPoolStallThreshold(50*time.Millisecond),
+		WithPoolJobLocker(jobLockerFunc(func(ctx context.Context, queue string) (*Job, error) {
+			if !jobAvailable.Load() {
+				return nil, nil
+			}
+			return poll(ctx, queue)
+		})),
+	)
+	require.NoError(t, err)
+
+	h := p.Health()
+	assert.False(t, h.Healthy)
+	assert.Equal(t, WorkerStatusStopped, h.Status)
+	assert.Len(t, h.Workers, 3)
+
+	stopPolling := pollHealth(func() { p.Health() })
+	defer stopPolling()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var grp errgroup.Group
+	grp.Go(func() error {
+		return p.Run(ctx)
+	})
+
+	require.Eventually(t, func() bool {
+		return p.Health().Healthy
+	}, 5*time.Second, time.Millisecond)
+
+	jobAvailable.Store(true)
+	started.Wait()
+
+	// single stalled worker makes the whole pool not healthy
+	require.Eventually(t, func() bool {
+		return !p.Health().Healthy
+	}, 5*time.Second, time.Millisecond)
+	h = p.Health()
+	assert.Equal(t, WorkerStatusRunning, h.Status)
+	var stalled int
+	for _, wh := range h.Workers {
+		if !wh.Healthy {
+			stalled++
+			assert.True(t, wh.Working)
+		}
+	}
+	assert.Equal(t, 1, stalled)
+
+	close(release)
+	require.Eventually(t, func() bool {
+		return p.Health().Healthy
+	}, 5*time.Second, time.Millisecond)
+
+	cancel()
+	require.NoError(t, grp.Wait())
+	assert.False(t, p.Health().Healthy)
+}
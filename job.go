@@ -1,13 +1,18 @@
 package gue
 
 import (
+	"bytes"
 	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
+	"io"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/oklog/ulid/v2"
+	"go.opentelemetry.io/otel/propagation"
 
 	"github.com/vortex14/gue/v7/adapter"
 )
@@ -15,6 +20,9 @@ import (
 // JobPriority is the wrapper type for Job.Priority
 type JobPriority int16
 
+// maxJobLogSize is the max size of the logs captured with Job.Logf, the rest is truncated.
+const maxJobLogSize = 64 << 10
+
 // Some shortcut values for JobPriority that can be any, but chances are high that one of these will be the most used.
 const (
 	JobPriorityHighest JobPriority = -32768
@@ -72,19 +80,113 @@ type Job struct {
 	mu      sync.Mutex
 	deleted bool
 	tx      adapter.Tx
+	// txTaken is set once the transaction is handed out with Tx(), so the worker stops the automatic heartbeat,
+	// as the transaction connection can not be used concurrently by the heartbeat and the handler.
+	txTaken bool
+	client  *Client
+	batch   *jobBatch
 	backoff Backoff
 	logger  adapter.Logger
+	now     func() time.Time
+	logs    jobLog
+	next    []*Job
+
+	// savepoint is set while the batch job savepoint is active, see startSavepoint
+	savepoint bool
+
+	// traceContext is the trace context propagated from the enqueuing code
+	traceContext propagation.MapCarrier
+}
+
+// NewJob builds a new Job of the given type with args marshalled to JSON.
+func NewJob(jobType string, args any, options ...JobOption) (*Job, error) {
+	if jobType == "" {
+		return nil, ErrMissingType
+	}
+
+	data, err := json.Marshal(args)
+	if err != nil {
+		return nil, fmt.Errorf("could not marshal job args: %w", err)
+	}
+
+	j := Job{Type: jobType, Args: data}
+	for _, option := range options {
+		option(&j)
+	}
+
+	return &j, nil
+}
+
+// UnmarshalArgs decodes JSON-encoded job Args into dest.
+func (j *Job) UnmarshalArgs(dest any) error {
+	if err := json.Unmarshal(j.Args, dest); err != nil {
+		return fmt.Errorf("could not unmarshal job args: %w", err)
+	}
+
+	return nil
 }
 
 // Tx returns DB transaction that this job is locked to. You may use
 // it as you please until you call Done(). At that point, this transaction
 // will be committed. This function will return nil if the Job's
 // transaction was closed with Done().
+//
+// Worker stops the automatic job heartbeat (see WithWorkerHeartbeat) once Tx is called, so the handler queries
+// never run concurrently with the heartbeat ones on the same connection.
 func (j *Job) Tx() adapter.Tx {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.txTaken = j.tx != nil
 	return j.tx
 }
 
-// Delete marks this job as complete by deleting it from the database.
+// Client returns the Client this job was locked with. This function will return nil for the jobs
+// that were not locked by the Client, e.g. created with NewJob.
+func (j *Job) Client() *Client {
+	return j.client
+}
+
+// EnqueueInTx adds a job to the queue within the transaction this job is locked to, so the new job becomes visible
+// to the workers only when the current job transaction is committed. This allows to chain jobs atomically
+// with the current job completion without risking duplicates in case of crash.
+//
+// Please note that the job transaction is committed when the handler returns an error as well, since the error
+// is stored within the same transaction, so the new job is enqueued in this case too. EnqueueInTx is valid only
+// until Done() is called, ErrJobNotLocked is returned after that.
+func (j *Job) EnqueueInTx(ctx context.Context, newJob *Job) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.tx == nil || j.client == nil {
+		return ErrJobNotLocked
+	}
+
+	return j.client.EnqueueTx(ctx, newJob, j.tx)
+}
+
+// EnqueueNext stages the job to be enqueued when this job completes successfully. Staged jobs are inserted within
+// the transaction this job is locked to right before the job is deleted with Delete(), so either the job is
+// completed and all the staged jobs are enqueued, or none of this happens. Staged jobs are discarded if the job
+// fails with Error() or is postponed, so the next jobs are never enqueued for the job that is going to be retried.
+//
+// EnqueueNext may be called several times to chain several jobs, possibly to different queues. EnqueueNext is valid
+// only until Done() is called, ErrJobNotLocked is returned after that.
+func (j *Job) EnqueueNext(next *Job) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.tx == nil || j.client == nil {
+		return ErrJobNotLocked
+	}
+
+	j.next = append(j.next, next)
+	return nil
+}
+
+// Delete marks this job as complete by deleting it from the database. Jobs staged with EnqueueNext are enqueued
+// within the same transaction before the job is deleted. ErrJobNotLocked is returned if the job lock was already
+// released with Done().
 //
 // You must also later call Done() to return this job's database connection to
 // the pool. If you got the job from the worker - it will take care of cleaning up the job and resources,
@@ -93,10 +195,21 @@ func (j *Job) Delete(ctx context.Context) error {
 	j.mu.Lock()
 	defer j.mu.Unlock()
 
+	if j.tx == nil {
+		return ErrJobNotLocked
+	}
+
 	if j.deleted {
 		return nil
 	}
 
+	if len(j.next) > 0 {
+		if err := j.client.EnqueueBatchTx(ctx, j.next, j.tx); err != nil {
+			return fmt.Errorf("could not enqueue next jobs: %w", err)
+		}
+		j.next = nil
+	}
+
 	_, err := j.tx.Exec(ctx, `DELETE FROM gue_jobs WHERE job_id = $1`, j.ID.String())
 	if err != nil {
 		return err
@@ -106,8 +219,44 @@ func (j *Job) Delete(ctx context.Context) error {
 	return nil
 }
 
-// Done commits transaction that marks job as done. If you got the job from the worker - it will take care of
-// cleaning up the job and resources, no need to do this manually in a WorkFunc.
+// Heartbeat touches the job within its transaction to show that the job is still being worked. This keeps the
+// transaction holding the job lock active, so it is not terminated by the server-side idle transaction timeouts,
+// and detects broken connection, that means the lock is lost, as early as possible.
+//
+// Heartbeat is safe to be called concurrently with other Job methods, but not with the queries executed
+// directly on the Job transaction returned by Tx().
+func (j *Job) Heartbeat(ctx context.Context) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	return j.heartbeat(ctx)
+}
+
+// autoHeartbeat is the Heartbeat called by the worker in the background. It does nothing and reports that
+// the heartbeat must be stopped once the transaction was handed out to the handler with Tx().
+func (j *Job) autoHeartbeat(ctx context.Context) (stopped bool, err error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.txTaken {
+		return true, nil
+	}
+
+	return false, j.heartbeat(ctx)
+}
+
+func (j *Job) heartbeat(ctx context.Context) error {
+	if j.tx == nil {
+		return ErrJobNotLocked
+	}
+
+	_, err := j.tx.Exec(ctx, `UPDATE gue_jobs SET updated_at = $1 WHERE job_id = $2`, j.now().UTC(), j.ID.String())
+	return err
+}
+
+// Done commits transaction that marks job as done and releases the job lock. Calling Done on the job that is already
+// done is a no-op. If you got the job from the worker - it will take care of cleaning up the job and resources,
+// no need to do this manually in a WorkFunc.
 func (j *Job) Done(ctx context.Context) error {
 	j.mu.Lock()
 	defer j.mu.Unlock()
@@ -117,6 +266,16 @@ func (j *Job) Done(ctx context.Context) error {
 		return nil
 	}
 
+	if j.batch != nil {
+		// transaction is shared between all the jobs in the batch, so it is committed by the last one
+		err := j.releaseSavepoint(ctx)
+		if doneErr := j.batch.done(ctx); doneErr != nil {
+			err = doneErr
+		}
+		j.tx = nil
+		return err
+	}
+
 	if err := j.tx.Commit(ctx); err != nil {
 		return err
 	}
@@ -126,6 +285,79 @@ func (j *Job) Done(ctx context.Context) error {
 	return nil
 }
 
+const batchJobSavepoint = "gue_batch_job"
+
+// startSavepoint starts the savepoint for the batch job that is going to be worked, so the job changes can be
+// rolled back on Done without affecting the other jobs in the batch if the job aborts the shared transaction.
+// Batch jobs must be worked one by one, as the savepoint is released only when the job is done.
+func (j *Job) startSavepoint(ctx context.Context) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.tx == nil || j.batch == nil {
+		return ErrJobNotLocked
+	}
+
+	if _, err := j.tx.Exec(ctx, "SAVEPOINT "+batchJobSavepoint); err != nil {
+		return err
+	}
+
+	j.savepoint = true
+	return nil
+}
+
+// releaseSavepoint releases the batch job savepoint if it is active. If the job aborted the shared transaction,
+// its changes are rolled back to the savepoint, so the job is released back to the queue as is, the same way
+// as the single job which transaction failed to commit. Must be called with the job mutex locked.
+func (j *Job) releaseSavepoint(ctx context.Context) error {
+	if !j.savepoint {
+		return nil
+	}
+	j.savepoint = false
+
+	_, err := j.tx.Exec(ctx, "RELEASE SAVEPOINT "+batchJobSavepoint)
+	if err == nil {
+		return nil
+	}
+
+	if _, rbErr := j.tx.Exec(ctx, "ROLLBACK TO SAVEPOINT "+batchJobSavepoint); rbErr != nil {
+		return fmt.Errorf("could not roll back batch job changes (release error: %v): %w", err, rbErr)
+	}
+
+	return fmt.Errorf("batch job changes are rolled back: %w", err)
+}
+
+// jobBatch is the transaction shared between the jobs locked together with Client.LockJobs.
+type jobBatch struct {
+	mu      sync.Mutex
+	tx      adapter.Tx
+	pending int
+	// err is the result of the transaction commit, set once all the batch jobs are done
+	err error
+}
+
+// done marks one of the batch jobs as done and commits the transaction when all the batch jobs are done.
+func (b *jobBatch) done(ctx context.Context) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.pending--
+	if b.pending > 0 {
+		return nil
+	}
+
+	b.err = b.tx.Commit(ctx)
+	return b.err
+}
+
+// commitErr returns the error the batch transaction was committed with.
+func (b *jobBatch) commitErr() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.err
+}
+
 // Error marks the job as failed and schedules it to be reworked. An error
 // message or backtrace can be provided as msg, which will be saved on the job.
 // It will also increase the error count.
@@ -133,8 +365,12 @@ func (j *Job) Done(ctx context.Context) error {
 // This call marks job as done and releases (commits) transaction,
 // so calling Done() is not required, although calling it will not cause any issues.
 // If you got the job from the worker - it will take care of cleaning up the job and resources,
-// no need to do this manually in a WorkFunc.
+// no need to do this manually in a WorkFunc. ErrJobNotLocked is returned if the job lock was already released.
 func (j *Job) Error(ctx context.Context, jErr error) (err error) {
+	if !j.locked() {
+		return ErrJobNotLocked
+	}
+
 	defer func() {
 		doneErr := j.Done(ctx)
 		if doneErr != nil {
@@ -142,8 +378,10 @@ func (j *Job) Error(ctx context.Context, jErr error) (err error) {
 		}
 	}()
 
+	j.discardNext()
+
 	errorCount := j.ErrorCount + 1
-	now := time.Now().UTC()
+	now := j.now().UTC()
 	newRunAt := j.calculateErrorRunAt(jErr, now, errorCount)
 	if newRunAt.IsZero() {
 		j.logger.Info(
@@ -157,19 +395,151 @@ func (j *Job) Error(ctx context.Context, jErr error) (err error) {
 		return
 	}
 
-	_, err = j.tx.Exec(
+	return j.execLocked(
 		ctx,
 		`UPDATE gue_jobs SET error_count = $1, run_at = $2, last_error = $3, updated_at = $4 WHERE job_id = $5`,
-		errorCount, newRunAt, jErr.Error(), now, j.ID.String(),
+		errorCount, newRunAt, j.lastErrorMessage(jErr), now, j.ID.String(),
 	)
+}
+
+// lastErrorMessage builds the job last error message with the job-scoped logs appended.
+func (j *Job) lastErrorMessage(jErr error) string {
+	lastError := jErr.Error()
+	if logs := j.Logs(); logs != "" {
+		lastError += "\n\njob logs:\n" + logs
+	}
+
+	return lastError
+}
+
+// Logf captures formatted log line in the job-scoped logs, so it is easier to debug the specific job than grepping
+// the global worker logs. Captured logs are kept in memory while the job is being worked, they are available
+// with Logs() (e.g. in the job done hooks) and are appended to the job last error when the job fails.
+// Logs are persisted on failure only: successfully worked job is deleted and there is no finished jobs archive
+// to store them, so use the job done hooks to ship the logs of the succeeded jobs elsewhere if they are needed.
+// Logs are limited to 64KiB, the rest is truncated. Logf is safe to be called concurrently.
+func (j *Job) Logf(format string, args ...any) {
+	line := fmt.Sprintf(format, args...)
+	if !strings.HasSuffix(line, "\n") {
+		line += "\n"
+	}
+
+	_, _ = j.logs.Write([]byte(line))
+}
+
+// LogWriter returns io.Writer that writes to the job-scoped logs, so any logger can be redirected there
+// in the handler. See Logf for details.
+func (j *Job) LogWriter() io.Writer {
+	return &j.logs
+}
+
+// Logs returns the job-scoped logs captured with Logf or LogWriter.
+func (j *Job) Logs() string {
+	return j.logs.String()
+}
+
+// Postpone reschedules the job to run at the given time without marking it as failed, so neither error count
+// nor last error are changed.
+//
+// This call marks job as done and releases (commits) transaction,
+// so calling Done() is not required, although calling it will not cause any issues.
+// If you got the job from the worker - it will take care of cleaning up the job and resources,
+// no need to do this manually in a WorkFunc, return error built with ErrPostponeJobIn or ErrPostponeJobAt instead.
+// ErrJobNotLocked is returned if the job lock was already released.
+func (j *Job) Postpone(ctx context.Context, runAt time.Time) (err error) {
+	if !j.locked() {
+		return ErrJobNotLocked
+	}
+
+	defer func() {
+		doneErr := j.Done(ctx)
+		if doneErr != nil {
+			err = fmt.Errorf("failed to mark job as done (original error: %v): %w", err, doneErr)
+		}
+	}()
+
+	j.discardNext()
+
+	return j.execLocked(
+		ctx,
+		`UPDATE gue_jobs SET run_at = $1, updated_at = $2 WHERE job_id = $3`,
+		runAt, j.now().UTC(), j.ID.String(),
+	)
+}
+
+// locked checks if the job lock is not released yet.
+func (j *Job) locked() bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	return j.tx != nil
+}
+
+// execLocked executes the query within the job transaction unless the job lock is already released, so the query
+// never affects the job that may be locked by another worker by that time.
+func (j *Job) execLocked(ctx context.Context, sql string, args ...any) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
 
+	if j.tx == nil {
+		return ErrJobNotLocked
+	}
+
+	_, err := j.tx.Exec(ctx, sql, args...)
 	return err
 }
 
+// discardNext drops the jobs staged with EnqueueNext.
+func (j *Job) discardNext() {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.next = nil
+}
+
+// moveToQueue marks the job as failed the same way Error does and moves it to the given queue to be run immediately.
+// This call marks job as done and releases (commits) transaction.
+func (j *Job) moveToQueue(ctx context.Context, queue string, jErr error) (err error) {
+	if !j.locked() {
+		return ErrJobNotLocked
+	}
+
+	defer func() {
+		doneErr := j.Done(ctx)
+		if doneErr != nil {
+			err = fmt.Errorf("failed to mark job as done (original error: %v): %w", err, doneErr)
+		}
+	}()
+
+	j.discardNext()
+
+	now := j.now().UTC()
+	return j.execLocked(
+		ctx,
+		`UPDATE gue_jobs SET queue = $1, error_count = $2, run_at = $3, last_error = $4, updated_at = $3 WHERE job_id = $5`,
+		queue, j.ErrorCount+1, now, j.lastErrorMessage(jErr), j.ID.String(),
+	)
+}
+
+// discard persists the given error as the job last error and deletes the job in the same transaction, so the last
+// error is visible to whatever observes the deleted row, e.g. audit triggers or change data capture.
+// This call does not mark job as done, the transaction is committed by the caller.
+func (j *Job) discard(ctx context.Context, jErr error) error {
+	if err := j.execLocked(
+		ctx,
+		`UPDATE gue_jobs SET last_error = $1, updated_at = $2 WHERE job_id = $3`,
+		j.lastErrorMessage(jErr), j.now().UTC(), j.ID.String(),
+	); err != nil {
+		return fmt.Errorf("could not persist last error: %w", err)
+	}
+
+	return j.Delete(ctx)
+}
+
 func (j *Job) calculateErrorRunAt(err error, now time.Time, errorCount int32) time.Time {
 	errReschedule, ok := err.(ErrJobReschedule)
 	if ok {
-		return errReschedule.rescheduleJobAt()
+		return errReschedule.rescheduleJobAt(now)
 	}
 
 	backoff := j.backoff(int(errorCount))
@@ -179,3 +549,40 @@ func (j *Job) calculateErrorRunAt(err error, now time.Time, errorCount int32) ti
 
 	return now.Add(backoff)
 }
+
+// jobLog is the bounded buffer for the job-scoped logs.
+type jobLog struct {
+	mu        sync.Mutex
+	buf       bytes.Buffer
+	truncated bool
+}
+
+// Write implements io.Writer. Data that does not fit into maxJobLogSize is dropped silently.
+func (l *jobLog) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.truncated {
+		return len(p), nil
+	}
+
+	if available := maxJobLogSize - l.buf.Len(); len(p) > available {
+		l.buf.Write(p[:available])
+		l.truncated = true
+		return len(p), nil
+	}
+
+	l.buf.Write(p)
+	return len(p), nil
+}
+
+func (l *jobLog) String() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.truncated {
+		return l.buf.String() + "... [truncated]"
+	}
+
+	return l.buf.String()
+}
@@ -0,0 +1,102 @@
+package gue
+
+import (
+	"time"
+
+	"github.com/oklog/ulid/v2"
+)
+
+// JobOutcome is the result of the Job being worked by the Worker.
+type JobOutcome string
+
+const (
+	// JobOutcomeSucceeded means that the job handler finished successfully and the job was removed from the queue.
+	JobOutcomeSucceeded JobOutcome = "succeeded"
+	// JobOutcomeErrored means that the job handler returned an error and the job was scheduled to be retried.
+	JobOutcomeErrored JobOutcome = "errored"
+	// JobOutcomePanicked means that the job handler panicked and the job was scheduled to be retried.
+	JobOutcomePanicked JobOutcome = "panicked"
+	// JobOutcomeDiscarded means that the job handler returned an error and the job was removed from the queue
+	// as it is not going to be retried anymore.
+	JobOutcomeDiscarded JobOutcome = "discarded"
+	// JobOutcomeUnknownType means that there is no handler for the job type and the job was marked as errored.
+	JobOutcomeUnknownType JobOutcome = "unknown-type"
+	// JobOutcomePostponed means that the job was postponed either by the handler, by the job type limiter
+	// or because the job with the same dedup key was being worked.
+	JobOutcomePostponed JobOutcome = "postponed"
+	// JobOutcomeReleased means that the job was released back to the queue without being worked, e.g. when
+	// the worker rate limiter wait was interrupted. JobEventSink does not receive events for such jobs.
+	JobOutcomeReleased JobOutcome = "released"
+
+	// JobOutcomeNoJob means that there was no job available in the queue. It is returned by Worker.WorkOneOutcome only.
+	JobOutcomeNoJob JobOutcome = "no-job"
+	// JobOutcomeLockFailed means that the worker failed to lock the job. It is returned by Worker.WorkOneOutcome only.
+	JobOutcomeLockFailed JobOutcome = "lock-failed"
+)
+
+// JobEvent describes the Job worked by the Worker.
+type JobEvent struct {
+	JobID    ulid.ULID
+	Type     string
+	Queue    string
+	WorkerID string
+	Outcome  JobOutcome
+	// Duration is the time the job was processed for, including the hooks and marking the job as done.
+	Duration time.Duration
+	// Error is the error message for the jobs that were not worked successfully, empty otherwise.
+	Error string
+
+	err error
+}
+
+func (e *JobEvent) setOutcome(outcome JobOutcome, err error) {
+	e.Outcome, e.err, e.Error = outcome, err, ""
+	if err != nil {
+		e.Error = err.Error()
+	}
+}
+
+// WorkResult describes the result of the single Worker.WorkOneResult call.
+type WorkResult struct {
+	// JobID and Type describe the worked job, empty if no job was locked.
+	JobID ulid.ULID
+	Type  string
+	// Outcome is JobOutcomeNoJob or JobOutcomeLockFailed if no job was locked, one of the JobEvent outcomes otherwise.
+	Outcome JobOutcome
+	// Err is the error the job failed with or the lock error, nil otherwise.
+	Err error
+	// Duration is the time the job was processed for, zero if no job was locked.
+	Duration time.Duration
+	// DidWork is the same flag Worker.WorkOne returns.
+	DidWork bool
+}
+
+func newWorkResult(e JobEvent, didWork bool) WorkResult {
+	return WorkResult{
+		JobID:    e.JobID,
+		Type:     e.Type,
+		Outcome:  e.Outcome,
+		Err:      e.err,
+		Duration: e.Duration,
+		DidWork:  didWork,
+	}
+}
+
+// JobEventSink receives JobEvent for every job worked by the Worker. Sink is called synchronously by the Worker
+// after the job transaction is committed, so the job changes are already visible when the event is received.
+// Sink implementation must be fast and must not block, otherwise it stalls the job processing.
+type JobEventSink func(e JobEvent)
+
+// NewJobEventChannel builds JobEventSink that sends events to the returned channel with the given buffer size.
+// Sink never blocks the Worker: if the channel buffer is full, the event is dropped. The channel is never closed,
+// as the same sink may be shared between several Workers, e.g. in the WorkerPool.
+func NewJobEventChannel(buffer int) (JobEventSink, <-chan JobEvent) {
+	ch := make(chan JobEvent, buffer)
+
+	return func(e JobEvent) {
+		select {
+		case ch <- e:
+		default:
+		}
+	}, ch
+}
@@ -0,0 +1,93 @@
+package gue
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	adapterTesting "github.com/vortex14/gue/v7/adapter/testing"
+)
+
+func TestNewJobEventChannel(t *testing.T) {
+	sink, events := NewJobEventChannel(2)
+
+	sink(JobEvent{Type: "first"})
+	sink(JobEvent{Type: "second"})
+	// buffer is full, event is dropped without blocking
+	sink(JobEvent{Type: "third"})
+
+	assert.Equal(t, "first", (<-events).Type)
+	assert.Equal(t, "second", (<-events).Type)
+
+	select {
+	case e := <-events:
+		t.Fatalf("unexpected event: %+v", e)
+	default:
+	}
+}
+
+func TestWorkerEventSink(t *testing.T) {
+	ctx := context.Background()
+
+	c, err := NewClient(nil)
+	require.NoError(t, err)
+
+	wm := WorkMap{
+		"succeeded": func(ctx context.Context, j *Job) error { return nil },
+		"errored":   func(ctx context.Context, j *Job) error { return errors.New("handler failed") },
+		"discarded": func(ctx context.Context, j *Job) error { return ErrDiscardJob("handler gave up") },
+		"panicked":  func(ctx context.Context, j *Job) error { panic("handler panicked") },
+		"postponed": func(ctx context.Context, j *Job) error { return ErrPostponeJobIn(time.Minute, "not yet") },
+	}
+
+	for jobType, tc := range map[string]struct {
+		outcome JobOutcome
+		err     string
+	}{
+		"succeeded":    {outcome: JobOutcomeSucceeded},
+		"errored":      {outcome: JobOutcomeErrored, err: "handler failed"},
+		"discarded":    {outcome: JobOutcomeDiscarded, err: "handler gave up"},
+		"panicked":     {outcome: JobOutcomePanicked, err: "handler panicked"},
+		"postponed":    {outcome: JobOutcomePostponed},
+		"unknown-type": {outcome: JobOutcomeUnknownType, err: `unknown job type: "unknown-type"`},
+	} {
+		t.Run(jobType, func(t *testing.T) {
+			tx := new(adapterTesting.Tx)
+			tx.Queryable.On("Exec", mock.Anything, mock.Anything, mock.Anything).Return(nil, nil)
+			tx.Mock.On("Commit", mock.Anything).Return(nil).Once()
+
+			j := &Job{Type: jobType, Queue: "events", tx: tx, client: c, backoff: c.backoff, logger: c.logger, now: c.now}
+
+			sink, events := NewJobEventChannel(1)
+			w, err := NewWorker(
+				c, wm,
+				WithWorkerID("events-worker"),
+				WithWorkerEventSink(sink),
+				withWorkerPollFunc(func(context.Context, string) (*Job, error) { return j, nil }),
+			)
+			require.NoError(t, err)
+
+			require.True(t, w.WorkOne(ctx))
+			tx.Mock.AssertExpectations(t)
+
+			require.Len(t, events, 1)
+			e := <-events
+			assert.Equal(t, j.ID, e.JobID)
+			assert.Equal(t, jobType, e.Type)
+			assert.Equal(t, "events", e.Queue)
+			assert.Equal(t, "events-worker", e.WorkerID)
+			assert.Equal(t, tc.outcome, e.Outcome)
+			assert.Positive(t, e.Duration)
+			if tc.err == "" {
+				assert.Empty(t, e.Error)
+			} else {
+				assert.Contains(t, e.Error, tc.err)
+			}
+		})
+	}
+}
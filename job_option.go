@@ -0,0 +1,34 @@
+package gue
+
+import "time"
+
+// JobOption defines a type that allows to set job properties when the job is built with NewJob.
+type JobOption func(*Job)
+
+// WithJobQueue sets the queue name for the job.
+func WithJobQueue(queue string) JobOption {
+	return func(j *Job) {
+		j.Queue = queue
+	}
+}
+
+// WithJobPriority sets the priority for the job.
+func WithJobPriority(priority JobPriority) JobOption {
+	return func(j *Job) {
+		j.Priority = priority
+	}
+}
+
+// WithJobRunAt sets the time that the job should be executed at.
+func WithJobRunAt(runAt time.Time) JobOption {
+	return func(j *Job) {
+		j.RunAt = runAt
+	}
+}
+
+// WithJobCluster sets the cluster name for the job.
+func WithJobCluster(cluster string) JobOption {
+	return func(j *Job) {
+		j.Cluster = cluster
+	}
+}
@@ -0,0 +1,33 @@
+package gue
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJobOptions(t *testing.T) {
+	jobWithDefaults, err := NewJob("MyJob", nil)
+	require.NoError(t, err)
+	assert.Equal(t, defaultQueueName, jobWithDefaults.Queue)
+	assert.Equal(t, JobPriorityDefault, jobWithDefaults.Priority)
+	assert.True(t, jobWithDefaults.RunAt.IsZero())
+	assert.Empty(t, jobWithDefaults.Cluster)
+
+	runAt := time.Now().Add(time.Hour)
+	jobWithOptions, err := NewJob(
+		"MyJob",
+		nil,
+		WithJobQueue("custom"),
+		WithJobPriority(JobPriorityHigh),
+		WithJobRunAt(runAt),
+		WithJobCluster("eu-1"),
+	)
+	require.NoError(t, err)
+	assert.Equal(t, "custom", jobWithOptions.Queue)
+	assert.Equal(t, JobPriorityHigh, jobWithOptions.Priority)
+	assert.Equal(t, runAt, jobWithOptions.RunAt)
+	assert.Equal(t, "eu-1", jobWithOptions.Cluster)
+}
@@ -2,9 +2,16 @@ package gue
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/oklog/ulid/v2"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 
 	"github.com/vortex14/gue/v7/adapter"
@@ -81,3 +88,457 @@ func testJobTxUnwrapTx(t *testing.T, name string, connPool adapter.ConnPool) {
 		require.NoError(t, err)
 	}
 }
+
+type nestedJobArgs struct {
+	Name      string            `json:"name"`
+	CreatedAt time.Time         `json:"created_at"`
+	Tags      []string          `json:"tags"`
+	Meta      map[string]string `json:"meta"`
+	Child     *nestedJobArgs    `json:"child,omitempty"`
+}
+
+func TestNewJob(t *testing.T) {
+	_, err := NewJob("", nil)
+	require.ErrorIs(t, err, ErrMissingType)
+
+	_, err = NewJob("MyJob", make(chan int))
+	require.Error(t, err)
+
+	args := nestedJobArgs{
+		Name:      "parent",
+		CreatedAt: time.Date(2030, 1, 2, 3, 4, 5, 6, time.FixedZone("UTC+3", 3*60*60)),
+		Tags:      []string{"foo", "bar"},
+		Meta:      map[string]string{"key": "value"},
+		Child: &nestedJobArgs{
+			Name:      "child",
+			CreatedAt: time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC),
+		},
+	}
+
+	j, err := NewJob("MyJob", args)
+	require.NoError(t, err)
+	assert.Equal(t, "MyJob", j.Type)
+	assert.JSONEq(t, `{
+  "name": "parent",
+  "created_at": "2030-01-02T03:04:05.000000006+03:00",
+  "tags": ["foo", "bar"],
+  "meta": {"key": "value"},
+  "child": {"name": "child", "created_at": "2030-01-02T03:04:05Z", "tags": null, "meta": null}
+}`, string(j.Args))
+
+	var decoded nestedJobArgs
+	err = j.UnmarshalArgs(&decoded)
+	require.NoError(t, err)
+	assert.Equal(t, args.Name, decoded.Name)
+	assert.True(t, args.CreatedAt.Equal(decoded.CreatedAt))
+	assert.Equal(t, args.Tags, decoded.Tags)
+	assert.Equal(t, args.Meta, decoded.Meta)
+	require.NotNil(t, decoded.Child)
+	assert.Equal(t, args.Child.Name, decoded.Child.Name)
+	assert.True(t, args.Child.CreatedAt.Equal(decoded.Child.CreatedAt))
+}
+
+func TestJob_UnmarshalArgs(t *testing.T) {
+	for name, tc := range map[string][]byte{
+		"empty":        {},
+		"nil":          nil,
+		"invalid json": []byte(`{invalid]json>`),
+		"wrong type":   []byte(`{"name": 42}`),
+	} {
+		t.Run(name, func(t *testing.T) {
+			j := Job{Type: "MyJob", Args: tc}
+
+			var args nestedJobArgs
+			err := j.UnmarshalArgs(&args)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestJob_Logf(t *testing.T) {
+	j := Job{Type: "MyJob"}
+	assert.Empty(t, j.Logs())
+
+	j.Logf("processing item %d", 1)
+	j.Logf("processed item %d\n", 1)
+	_, err := fmt.Fprint(j.LogWriter(), "written directly\n")
+	require.NoError(t, err)
+
+	assert.Equal(t, "processing item 1\nprocessed item 1\nwritten directly\n", j.Logs())
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			j.Logf("concurrent")
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, 10, strings.Count(j.Logs(), "concurrent\n"))
+}
+
+func TestJob_LogfTruncated(t *testing.T) {
+	j := Job{Type: "MyJob"}
+
+	line := strings.Repeat("a", 1023)
+	for i := 0; i < 100; i++ {
+		j.Logf(line)
+	}
+
+	logs := j.Logs()
+	assert.True(t, strings.HasSuffix(logs, "... [truncated]"))
+	assert.Equal(t, maxJobLogSize, len(strings.TrimSuffix(logs, "... [truncated]")))
+
+	// everything after the truncation is dropped
+	j.Logf("the last line")
+	assert.Equal(t, logs, j.Logs())
+}
+
+func TestJob_ErrorLogs(t *testing.T) {
+	for name, openFunc := range adapterTesting.AllAdaptersOpenTestPool {
+		t.Run(name, func(t *testing.T) {
+			testJobErrorLogs(t, openFunc(t))
+		})
+	}
+}
+
+func testJobErrorLogs(t *testing.T, connPool adapter.ConnPool) {
+	ctx := context.Background()
+
+	c, err := NewClient(connPool)
+	require.NoError(t, err)
+
+	job := &Job{Type: "MyJob"}
+	err = c.Enqueue(ctx, job)
+	require.NoError(t, err)
+
+	j, err := c.LockJob(ctx, "")
+	require.NoError(t, err)
+	require.NotNil(t, j)
+
+	j.Logf("calling remote API, attempt %d", 1)
+	err = j.Error(ctx, errors.New("remote API failed"))
+	require.NoError(t, err)
+
+	j2, err := c.LockJobByID(ctx, job.ID)
+	require.NoError(t, err)
+	require.NotNil(t, j2)
+
+	t.Cleanup(func() {
+		err := j2.Done(ctx)
+		assert.NoError(t, err)
+	})
+
+	assert.Equal(t, "remote API failed\n\njob logs:\ncalling remote API, attempt 1\n", j2.LastError.String)
+	// logs are not carried over between the job runs
+	assert.Empty(t, j2.Logs())
+}
+
+func TestJob_EnqueueInTxClosed(t *testing.T) {
+	j := Job{Type: "MyJob"}
+	assert.Nil(t, j.Client())
+
+	err := j.EnqueueInTx(context.Background(), &Job{Type: "MyNextJob"})
+	assert.ErrorIs(t, err, adapter.ErrTxClosed)
+}
+
+func TestJob_EnqueueInTx(t *testing.T) {
+	for name, openFunc := range adapterTesting.AllAdaptersOpenTestPool {
+		t.Run(name, func(t *testing.T) {
+			testJobEnqueueInTx(t, openFunc(t))
+		})
+	}
+}
+
+func testJobEnqueueInTx(t *testing.T, connPool adapter.ConnPool) {
+	ctx := context.Background()
+
+	c, err := NewClient(connPool)
+	require.NoError(t, err)
+
+	err = c.Enqueue(ctx, &Job{Type: "MyJob", Queue: "first"})
+	require.NoError(t, err)
+
+	j, err := c.LockJob(ctx, "first")
+	require.NoError(t, err)
+	require.NotNil(t, j)
+	assert.Same(t, c, j.Client())
+
+	nextJob := &Job{Type: "MyNextJob", Queue: "next"}
+	err = j.EnqueueInTx(ctx, nextJob)
+	require.NoError(t, err)
+	require.NotEmpty(t, nextJob.ID)
+
+	// next job is not visible until the current job transaction is committed
+	jNext, err := c.LockJob(ctx, "next")
+	require.NoError(t, err)
+	assert.Nil(t, jNext)
+
+	err = j.Delete(ctx)
+	require.NoError(t, err)
+	err = j.Done(ctx)
+	require.NoError(t, err)
+
+	err = j.EnqueueInTx(ctx, &Job{Type: "MyNextJob", Queue: "next"})
+	assert.ErrorIs(t, err, adapter.ErrTxClosed)
+
+	jNext, err = c.LockJob(ctx, "next")
+	require.NoError(t, err)
+	require.NotNil(t, jNext)
+
+	t.Cleanup(func() {
+		err := jNext.Done(ctx)
+		assert.NoError(t, err)
+	})
+
+	assert.Equal(t, nextJob.ID, jNext.ID)
+}
+
+func TestJob_EnqueueNextClosed(t *testing.T) {
+	j := Job{Type: "MyJob"}
+
+	err := j.EnqueueNext(&Job{Type: "MyNextJob"})
+	assert.ErrorIs(t, err, adapter.ErrTxClosed)
+}
+
+func TestJob_EnqueueNext(t *testing.T) {
+	for name, openFunc := range adapterTesting.AllAdaptersOpenTestPool {
+		t.Run(name, func(t *testing.T) {
+			testJobEnqueueNext(t, openFunc(t))
+		})
+	}
+}
+
+func testJobEnqueueNext(t *testing.T, connPool adapter.ConnPool) {
+	ctx := context.Background()
+
+	c, err := NewClient(connPool)
+	require.NoError(t, err)
+
+	// deleting the job of this type fails, so the job completion can not be persisted
+	_, err = connPool.Exec(ctx, `
+CREATE OR REPLACE FUNCTION gue_test_fail_delete() RETURNS trigger AS $$
+BEGIN
+  IF OLD.job_type = 'MyFailingJob' THEN
+    RAISE EXCEPTION 'delete is not allowed';
+  END IF;
+  RETURN OLD;
+END;
+$$ LANGUAGE plpgsql;
+DROP TRIGGER IF EXISTS gue_test_fail_delete ON gue_jobs;
+CREATE TRIGGER gue_test_fail_delete BEFORE DELETE ON gue_jobs FOR EACH ROW EXECUTE PROCEDURE gue_test_fail_delete();
+`)
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		_, err := connPool.Exec(ctx, `DROP TRIGGER IF EXISTS gue_test_fail_delete ON gue_jobs; DROP FUNCTION IF EXISTS gue_test_fail_delete();`)
+		assert.NoError(t, err)
+	})
+
+	err = c.EnqueueBatch(ctx, []*Job{
+		{Type: "MyJob", Queue: "succeeded"},
+		{Type: "MyJob", Queue: "errored"},
+		{Type: "MyFailingJob", Queue: "failed-delete"},
+	})
+	require.NoError(t, err)
+
+	var handlerErr error
+	wm := WorkMap{
+		"MyJob": func(ctx context.Context, j *Job) error {
+			for _, queue := range []string{"next-1", "next-2"} {
+				if err := j.EnqueueNext(&Job{Type: "MyNextJob", Queue: queue, Args: []byte(j.Queue)}); err != nil {
+					return err
+				}
+			}
+			return handlerErr
+		},
+		"MyFailingJob": func(ctx context.Context, j *Job) error {
+			return j.EnqueueNext(&Job{Type: "MyNextJob", Queue: "next-1", Args: []byte(j.Queue)})
+		},
+	}
+
+	lockNext := func(queue string) []string {
+		var args []string
+		for {
+			j, err := c.LockJob(ctx, queue)
+			require.NoError(t, err)
+			if j == nil {
+				return args
+			}
+
+			args = append(args, string(j.Args))
+			t.Cleanup(func() {
+				err := j.Done(ctx)
+				assert.NoError(t, err)
+			})
+		}
+	}
+
+	// successful job enqueues all the chained jobs
+	w, err := NewWorker(c, wm, WithWorkerQueue("succeeded"))
+	require.NoError(t, err)
+	require.True(t, w.WorkOne(ctx))
+
+	// errored job discards the chained jobs and is kept for retry
+	handlerErr = errors.New("handler failed")
+	w, err = NewWorker(c, wm, WithWorkerQueue("errored"))
+	require.NoError(t, err)
+	require.True(t, w.WorkOne(ctx))
+
+	jErrored, err := c.LockJob(ctx, "errored")
+	require.NoError(t, err)
+	require.NotNil(t, jErrored)
+	assert.Equal(t, int32(1), jErrored.ErrorCount)
+	err = jErrored.Done(ctx)
+	require.NoError(t, err)
+
+	// job that could not be deleted leaks none of the chained jobs
+	jFailing, err := c.LockJob(ctx, "failed-delete")
+	require.NoError(t, err)
+	require.NotNil(t, jFailing)
+
+	err = wm["MyFailingJob"](ctx, jFailing)
+	require.NoError(t, err)
+	err = jFailing.Delete(ctx)
+	require.Error(t, err)
+	_ = jFailing.Done(ctx)
+
+	assert.Equal(t, []string{"succeeded"}, lockNext("next-1"))
+	assert.Equal(t, []string{"succeeded"}, lockNext("next-2"))
+
+	jFailing, err = c.LockJob(ctx, "failed-delete")
+	require.NoError(t, err)
+	require.NotNil(t, jFailing)
+	err = jFailing.Done(ctx)
+	require.NoError(t, err)
+}
+
+func TestJob_NotLocked(t *testing.T) {
+	ctx := context.Background()
+
+	c, err := NewClient(nil)
+	require.NoError(t, err)
+
+	tx := new(adapterTesting.Tx)
+	tx.Mock.On("Commit", mock.Anything).Return(nil).Once()
+
+	j := &Job{Type: "MyJob", tx: tx, client: c, backoff: c.backoff, logger: c.logger, now: c.now}
+	require.NoError(t, j.Done(ctx))
+	// calling Done again is a no-op
+	require.NoError(t, j.Done(ctx))
+
+	// no queries are executed once the job lock is released
+	assert.ErrorIs(t, j.Delete(ctx), ErrJobNotLocked)
+	assert.ErrorIs(t, j.Error(ctx, errors.New("late error")), ErrJobNotLocked)
+	assert.ErrorIs(t, j.Postpone(ctx, time.Now()), ErrJobNotLocked)
+	assert.ErrorIs(t, j.Heartbeat(ctx), ErrJobNotLocked)
+	assert.ErrorIs(t, j.EnqueueNext(&Job{Type: "MyNextJob"}), ErrJobNotLocked)
+	assert.ErrorIs(t, j.EnqueueInTx(ctx, &Job{Type: "MyNextJob"}), ErrJobNotLocked)
+	tx.Queryable.AssertNotCalled(t, "Exec")
+	tx.Mock.AssertExpectations(t)
+
+	// job that was never locked is not locked as well
+	assert.ErrorIs(t, (&Job{Type: "MyJob"}).Delete(ctx), ErrJobNotLocked)
+	assert.ErrorIs(t, ErrJobNotLocked, adapter.ErrTxClosed)
+}
+
+func TestJob_NotLockedAfterHandler(t *testing.T) {
+	ctx := context.Background()
+
+	c, err := NewClient(nil)
+	require.NoError(t, err)
+
+	tx := new(adapterTesting.Tx)
+	tx.Queryable.On("Exec", mock.Anything, mock.Anything, mock.Anything).Return(nil, nil).Once()
+	tx.Mock.On("Commit", mock.Anything).Return(nil).Once()
+
+	j := &Job{Type: "MyJob", tx: tx, client: c, backoff: c.backoff, logger: c.logger, now: c.now}
+
+	// handler leaks the job to the goroutine that outlives it
+	release := make(chan struct{})
+	lateErr := make(chan error)
+	wm := WorkMap{"MyJob": func(ctx context.Context, j *Job) error {
+		go func() {
+			<-release
+			lateErr <- j.Delete(ctx)
+		}()
+		return nil
+	}}
+
+	w, err := NewWorker(c, wm, withWorkerPollFunc(func(context.Context, string) (*Job, error) { return j, nil }))
+	require.NoError(t, err)
+	require.True(t, w.WorkOne(ctx))
+
+	close(release)
+	assert.ErrorIs(t, <-lateErr, ErrJobNotLocked)
+	tx.Queryable.AssertExpectations(t)
+	tx.Mock.AssertExpectations(t)
+}
+
+func TestJob_BatchSavepoint(t *testing.T) {
+	ctx := context.Background()
+
+	c, err := NewClient(nil)
+	require.NoError(t, err)
+
+	okJob := &Job{ID: ulid.Make(), Type: "MyJob", client: c, backoff: c.backoff, logger: c.logger, now: c.now}
+	abortingJob := &Job{ID: ulid.Make(), Type: "MyJob", client: c, backoff: c.backoff, logger: c.logger, now: c.now}
+
+	// tx mock behaves like the real transaction: once aborted it accepts rollback to savepoint only
+	var (
+		queries []string
+		aborted bool
+	)
+	errAborted := errors.New("current transaction is aborted")
+	record := func(args mock.Arguments) {
+		queries = append(queries, args.String(1))
+	}
+
+	tx := new(adapterTesting.Tx)
+	tx.Queryable.On("Exec", mock.Anything, mock.MatchedBy(func(query string) bool {
+		return aborted && !strings.HasPrefix(query, "ROLLBACK TO SAVEPOINT")
+	}), mock.Anything).Run(record).Return(nil, errAborted)
+	tx.Queryable.On("Exec", mock.Anything, mock.MatchedBy(func(query string) bool {
+		return strings.HasPrefix(query, "DELETE")
+	}), []any{abortingJob.ID.String()}).Run(func(args mock.Arguments) {
+		record(args)
+		aborted = true
+	}).Return(nil, errors.New("division by zero"))
+	tx.Queryable.On("Exec", mock.Anything, mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		record(args)
+		if strings.HasPrefix(args.String(1), "ROLLBACK TO SAVEPOINT") {
+			aborted = false
+		}
+	}).Return(nil, nil)
+	tx.Mock.On("Commit", mock.Anything).Return(nil).Once()
+
+	batch := &jobBatch{tx: tx, pending: 2}
+	for _, j := range []*Job{okJob, abortingJob} {
+		j.tx, j.batch = tx, batch
+	}
+
+	require.NoError(t, okJob.startSavepoint(ctx))
+	require.NoError(t, okJob.Delete(ctx))
+	require.NoError(t, okJob.Done(ctx))
+	tx.Mock.AssertNotCalled(t, "Commit", mock.Anything)
+
+	require.NoError(t, abortingJob.startSavepoint(ctx))
+	require.Error(t, abortingJob.Delete(ctx))
+	err = abortingJob.Done(ctx)
+	require.ErrorIs(t, err, errAborted)
+	assert.ErrorContains(t, err, "batch job changes are rolled back")
+
+	// only the aborting job changes are rolled back, so the shared transaction is committed with the ok job deleted
+	assert.Equal(t, []string{
+		"SAVEPOINT gue_batch_job",
+		"DELETE FROM gue_jobs WHERE job_id = $1",
+		"RELEASE SAVEPOINT gue_batch_job",
+		"SAVEPOINT gue_batch_job",
+		"DELETE FROM gue_jobs WHERE job_id = $1",
+		"RELEASE SAVEPOINT gue_batch_job",
+		"ROLLBACK TO SAVEPOINT gue_batch_job",
+	}, queries)
+	tx.Mock.AssertExpectations(t)
+	require.NoError(t, batch.commitErr())
+}
@@ -0,0 +1,38 @@
+package gue
+
+import (
+	"context"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+const defaultLimitedJobDelay = time.Second
+
+// Limiter decides whether the job can be worked right now. Limiter implementations must be safe for concurrent use.
+// Custom implementation, e.g. backed by the shared store, can be used to enforce the rate across several
+// application instances.
+type Limiter interface {
+	// Allow reports whether the job can be worked now. Job that is not allowed is postponed by the worker.
+	Allow(ctx context.Context, j *Job) (bool, error)
+}
+
+// NewRateLimiter returns in-memory Limiter that uses token bucket algorithm, where rps is the number of jobs allowed
+// per second and burst is the bucket size. Burst values less than 1 are considered as 1.
+// Non-positive rps allows no jobs at all.
+func NewRateLimiter(rps float64, burst int) Limiter {
+	if burst < 1 {
+		burst = 1
+	}
+
+	return &rateLimiter{l: rate.NewLimiter(rate.Limit(rps), burst)}
+}
+
+type rateLimiter struct {
+	l *rate.Limiter
+}
+
+// Allow implements Limiter.Allow()
+func (l *rateLimiter) Allow(context.Context, *Job) (bool, error) {
+	return l.l.Allow(), nil
+}
@@ -0,0 +1,35 @@
+package gue
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewRateLimiter(t *testing.T) {
+	ctx := context.Background()
+	j := &Job{Type: "MyJob"}
+
+	limiter := NewRateLimiter(0.001, 2)
+	for i := 0; i < 2; i++ {
+		allowed, err := limiter.Allow(ctx, j)
+		require.NoError(t, err)
+		assert.True(t, allowed)
+	}
+
+	allowed, err := limiter.Allow(ctx, j)
+	require.NoError(t, err)
+	assert.False(t, allowed)
+
+	// burst is at least 1
+	limiter = NewRateLimiter(0.001, 0)
+	allowed, err = limiter.Allow(ctx, j)
+	require.NoError(t, err)
+	assert.True(t, allowed)
+
+	allowed, err = limiter.Allow(ctx, j)
+	require.NoError(t, err)
+	assert.False(t, allowed)
+}
@@ -0,0 +1,58 @@
+package gue
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+
+	"go.opentelemetry.io/otel/propagation"
+)
+
+// traceArgsPrefix marks job args that are wrapped into the envelope with the propagated trace context.
+// Envelope format is "<prefix><JSON-encoded trace context>\x00<original args>", null byte can not appear
+// in the JSON-encoded value, so it safely separates the trace context from the original args.
+var traceArgsPrefix = []byte("\x00gue:trace:")
+
+// wrapTraceArgs injects trace context from ctx into the job args envelope. Args are returned as is
+// if there is no trace context to propagate.
+func wrapTraceArgs(ctx context.Context, propagator propagation.TextMapPropagator, args []byte) []byte {
+	carrier := propagation.MapCarrier{}
+	propagator.Inject(ctx, carrier)
+	if len(carrier) == 0 {
+		return args
+	}
+
+	traceContext, err := json.Marshal(carrier)
+	if err != nil {
+		return args
+	}
+
+	wrapped := make([]byte, 0, len(traceArgsPrefix)+len(traceContext)+1+len(args))
+	wrapped = append(wrapped, traceArgsPrefix...)
+	wrapped = append(wrapped, traceContext...)
+	wrapped = append(wrapped, 0)
+	wrapped = append(wrapped, args...)
+
+	return wrapped
+}
+
+// unwrapTraceArgs extracts original job args and trace context from the envelope. Args that are not wrapped
+// into the envelope are returned as is with nil trace context.
+func unwrapTraceArgs(args []byte) ([]byte, propagation.MapCarrier) {
+	if !bytes.HasPrefix(args, traceArgsPrefix) {
+		return args, nil
+	}
+
+	rest := args[len(traceArgsPrefix):]
+	sepIdx := bytes.IndexByte(rest, 0)
+	if sepIdx < 0 {
+		return args, nil
+	}
+
+	var carrier propagation.MapCarrier
+	if err := json.Unmarshal(rest[:sepIdx], &carrier); err != nil {
+		return args, nil
+	}
+
+	return rest[sepIdx+1:], carrier
+}
@@ -0,0 +1,204 @@
+package gue
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/propagation"
+	sdkTrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/vortex14/gue/v7/adapter"
+	adapterTesting "github.com/vortex14/gue/v7/adapter/testing"
+)
+
+func TestTraceArgs(t *testing.T) {
+	propagator := propagation.TraceContext{}
+	args := []byte(`{"foo":"bar"}`)
+
+	t.Run("no trace context", func(t *testing.T) {
+		wrapped := wrapTraceArgs(context.Background(), propagator, args)
+		assert.Equal(t, args, wrapped)
+
+		unwrapped, traceContext := unwrapTraceArgs(wrapped)
+		assert.Equal(t, args, unwrapped)
+		assert.Nil(t, traceContext)
+	})
+
+	t.Run("trace context", func(t *testing.T) {
+		tp := sdkTrace.NewTracerProvider()
+		ctx, span := tp.Tracer("test").Start(context.Background(), "enqueue")
+		defer span.End()
+
+		wrapped := wrapTraceArgs(ctx, propagator, args)
+		assert.NotEqual(t, args, wrapped)
+
+		unwrapped, traceContext := unwrapTraceArgs(wrapped)
+		assert.Equal(t, args, unwrapped)
+		require.NotNil(t, traceContext)
+
+		extracted := trace.SpanContextFromContext(propagator.Extract(context.Background(), traceContext))
+		assert.Equal(t, span.SpanContext().TraceID(), extracted.TraceID())
+		assert.Equal(t, span.SpanContext().SpanID(), extracted.SpanID())
+	})
+
+	t.Run("malformed envelope", func(t *testing.T) {
+		for _, malformed := range [][]byte{
+			append(append([]byte{}, traceArgsPrefix...), `{"traceparent":"foo"}`...),
+			append(append([]byte{}, traceArgsPrefix...), "not-a-json\x00args"...),
+		} {
+			unwrapped, traceContext := unwrapTraceArgs(malformed)
+			assert.Equal(t, malformed, unwrapped)
+			assert.Nil(t, traceContext)
+		}
+	})
+}
+
+func TestTracePropagation(t *testing.T) {
+	ctx := context.Background()
+	propagator := propagation.TraceContext{}
+
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdkTrace.NewTracerProvider(sdkTrace.WithSyncer(exporter))
+	tracer := tp.Tracer("test")
+
+	var storedArgs []byte
+	connPool := new(adapterTesting.ConnPool)
+	connPool.Queryable.On("Exec", mock.Anything, mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) {
+			storedArgs = args.Get(2).([]any)[5].([]byte)
+		}).
+		Return(nil, nil).
+		Once()
+
+	c, err := NewClient(connPool, WithClientTracePropagator(propagator))
+	require.NoError(t, err)
+
+	enqueueCtx, enqueueSpan := tracer.Start(ctx, "http-request")
+	err = c.Enqueue(enqueueCtx, &Job{Type: "MyJob", Args: []byte(`{"foo":"bar"}`)})
+	require.NoError(t, err)
+	enqueueSpan.End()
+
+	tx := new(adapterTesting.Tx)
+	tx.Queryable.On("Exec", mock.Anything, mock.Anything, mock.Anything).Return(nil, nil).Once()
+	tx.Mock.On("Commit", mock.Anything).Return(nil).Once()
+
+	// lock the job the same way the client does it
+	j := &Job{Type: "MyJob", Args: storedArgs, tx: tx, client: c, backoff: c.backoff, logger: c.logger, now: c.now}
+	j.Args, j.traceContext = unwrapTraceArgs(j.Args)
+
+	var handlerArgs []byte
+	wm := WorkMap{"MyJob": func(ctx context.Context, j *Job) error {
+		handlerArgs = j.Args
+		return nil
+	}}
+
+	w, err := NewWorker(
+		c, wm,
+		WithWorkerTracer(tracer),
+		WithWorkerTracePropagator(propagator),
+		withWorkerPollFunc(func(context.Context, string) (*Job, error) { return j, nil }),
+	)
+	require.NoError(t, err)
+
+	assert.True(t, w.WorkOne(ctx))
+	assert.Equal(t, []byte(`{"foo":"bar"}`), handlerArgs)
+	connPool.Queryable.AssertExpectations(t)
+	tx.Queryable.AssertExpectations(t)
+	tx.Mock.AssertExpectations(t)
+
+	spans := exporter.GetSpans()
+	var jobSpan, workerSpan *tracetest.SpanStub
+	for i := range spans {
+		switch spans[i].Name {
+		case "MyJob":
+			jobSpan = &spans[i]
+		case "Worker.WorkOne":
+			workerSpan = &spans[i]
+		}
+	}
+	require.NotNil(t, jobSpan)
+	require.NotNil(t, workerSpan)
+
+	// job span is the child of the enqueuing span and is linked to the worker span
+	assert.Equal(t, enqueueSpan.SpanContext().TraceID(), jobSpan.SpanContext.TraceID())
+	assert.Equal(t, enqueueSpan.SpanContext().SpanID(), jobSpan.Parent.SpanID())
+	require.Len(t, jobSpan.Links, 1)
+	assert.Equal(t, workerSpan.SpanContext.SpanID(), jobSpan.Links[0].SpanContext.SpanID())
+	assert.Equal(t, trace.SpanKindConsumer, jobSpan.SpanKind)
+}
+
+func TestTracePropagationNoTraceContext(t *testing.T) {
+	ctx := context.Background()
+
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdkTrace.NewTracerProvider(sdkTrace.WithSyncer(exporter))
+
+	tx := new(adapterTesting.Tx)
+	tx.Queryable.On("Exec", mock.Anything, mock.Anything, mock.Anything).Return(nil, nil).Once()
+	tx.Mock.On("Commit", mock.Anything).Return(nil).Once()
+
+	c, err := NewClient(nil)
+	require.NoError(t, err)
+
+	j := &Job{Type: "MyJob", Args: []byte(`{}`), tx: tx, client: c, backoff: c.backoff, logger: c.logger, now: c.now}
+	w, err := NewWorker(
+		c, WorkMap{"MyJob": func(ctx context.Context, j *Job) error { return nil }},
+		WithWorkerTracer(tp.Tracer("test")),
+		WithWorkerTracePropagator(propagation.TraceContext{}),
+		withWorkerPollFunc(func(context.Context, string) (*Job, error) { return j, nil }),
+	)
+	require.NoError(t, err)
+
+	assert.True(t, w.WorkOne(ctx))
+
+	// job without trace context is worked within the span that is the child of the worker span
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 2)
+	assert.Equal(t, "MyJob", spans[0].Name)
+	assert.Equal(t, "Worker.WorkOne", spans[1].Name)
+	assert.Equal(t, spans[1].SpanContext.SpanID(), spans[0].Parent.SpanID())
+	assert.Empty(t, spans[0].Links)
+}
+
+func TestClient_TracePropagation(t *testing.T) {
+	for name, openFunc := range adapterTesting.AllAdaptersOpenTestPool {
+		t.Run(name, func(t *testing.T) {
+			testClientTracePropagation(t, openFunc(t))
+		})
+	}
+}
+
+func testClientTracePropagation(t *testing.T, connPool adapter.ConnPool) {
+	ctx := context.Background()
+
+	tp := sdkTrace.NewTracerProvider()
+	c, err := NewClient(connPool, WithClientTracePropagator(propagation.TraceContext{}))
+	require.NoError(t, err)
+
+	enqueueCtx, enqueueSpan := tp.Tracer("test").Start(ctx, "http-request")
+	job := Job{Type: "MyJob", Args: []byte(`{"foo":"bar"}`)}
+	err = c.Enqueue(enqueueCtx, &job)
+	require.NoError(t, err)
+	enqueueSpan.End()
+
+	// enqueued job args are not changed
+	assert.Equal(t, []byte(`{"foo":"bar"}`), job.Args)
+
+	j, err := c.LockJobByID(ctx, job.ID)
+	require.NoError(t, err)
+	require.NotNil(t, j)
+
+	t.Cleanup(func() {
+		err := j.Done(ctx)
+		assert.NoError(t, err)
+	})
+
+	assert.Equal(t, []byte(`{"foo":"bar"}`), j.Args)
+	require.NotNil(t, j.traceContext)
+	assert.Contains(t, j.traceContext.Get("traceparent"), enqueueSpan.SpanContext().TraceID().String())
+}
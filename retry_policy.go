@@ -0,0 +1,239 @@
+package gue
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	exp "github.com/vgarvardt/backoff"
+)
+
+// ErrInvalidRetryPolicy is returned by ParseRetryPolicy when the policy spec can not be parsed.
+// Error is normally returned wrapped, so use `errors.Is(err, gue.ErrInvalidRetryPolicy)` to ensure this is the error
+// you're looking for.
+var ErrInvalidRetryPolicy = errors.New("invalid retry policy")
+
+// RetryPolicy defines how errored jobs are rescheduled.
+type RetryPolicy struct {
+	// Backoff calculates the delay before the next job run. Client backoff is used if not set.
+	Backoff Backoff
+
+	// MaxRetries is the max number of times the errored job is retried, the job is discarded after that.
+	// Zero value means no limit.
+	MaxRetries int
+}
+
+// backoff returns Backoff implementation that respects the policy max retries.
+func (p RetryPolicy) backoff(fallback Backoff) Backoff {
+	b := p.Backoff
+	if b == nil {
+		b = fallback
+	}
+
+	if p.MaxRetries <= 0 {
+		return b
+	}
+
+	return func(retries int) time.Duration {
+		if retries > p.MaxRetries {
+			return -1
+		}
+
+		return b(retries)
+	}
+}
+
+// ParseRetryPolicy parses retry policy spec, so that retry policies can be defined in the configuration.
+// Spec has the form of "<kind>(<param>=<value>, ...)", supported kinds and their params are:
+//
+//   - exponential(base=1s, multiplier=1.6, jitter=20%, cap=1h, max=0) - exponential backoff, see
+//     NewExponentialBackoff, values in the example are the defaults used for the omitted params;
+//   - constant(delay=30s, max=0) - constant backoff, see NewConstantBackoff, delay is required;
+//   - never() - errored jobs are never retried, see BackoffNever.
+//
+// Durations use time.ParseDuration format, jitter may be set either in percents or as a fraction, e.g. "0.2",
+// max is the max number of retries, zero means no limit.
+func ParseRetryPolicy(spec string) (RetryPolicy, error) {
+	kind, params, err := parseRetryPolicySpec(spec)
+	if err != nil {
+		return RetryPolicy{}, fmt.Errorf("%w %q: %v", ErrInvalidRetryPolicy, spec, err)
+	}
+
+	var policy RetryPolicy
+	switch kind {
+	case "exponential":
+		policy, err = parseExponentialRetryPolicy(params)
+	case "constant":
+		policy, err = parseConstantRetryPolicy(params)
+	case "never":
+		if len(params) > 0 {
+			err = errors.New("never policy has no params")
+		}
+		policy = RetryPolicy{Backoff: BackoffNever}
+	default:
+		err = fmt.Errorf("unknown policy kind %q", kind)
+	}
+	if err != nil {
+		return RetryPolicy{}, fmt.Errorf("%w %q: %v", ErrInvalidRetryPolicy, spec, err)
+	}
+
+	return policy, nil
+}
+
+func parseExponentialRetryPolicy(params map[string]string) (RetryPolicy, error) {
+	cfg := exp.Config{
+		BaseDelay:  time.Second,
+		Multiplier: 1.6,
+		Jitter:     0.2,
+		MaxDelay:   time.Hour,
+	}
+	var maxRetries int
+
+	for name, value := range params {
+		var err error
+		switch name {
+		case "base":
+			cfg.BaseDelay, err = parseRetryPolicyDuration(value)
+		case "multiplier":
+			cfg.Multiplier, err = strconv.ParseFloat(value, 64)
+			if err == nil && cfg.Multiplier < 1 {
+				err = errors.New("must not be less than 1")
+			}
+		case "jitter":
+			cfg.Jitter, err = parseRetryPolicyJitter(value)
+		case "cap":
+			cfg.MaxDelay, err = parseRetryPolicyDuration(value)
+		case "max":
+			maxRetries, err = parseRetryPolicyMax(value)
+		default:
+			err = errors.New("unknown param")
+		}
+		if err != nil {
+			return RetryPolicy{}, fmt.Errorf("param %q: %w", name, err)
+		}
+	}
+
+	if cfg.MaxDelay < cfg.BaseDelay {
+		return RetryPolicy{}, errors.New("cap must not be less than base")
+	}
+
+	return RetryPolicy{Backoff: NewExponentialBackoff(cfg), MaxRetries: maxRetries}, nil
+}
+
+func parseConstantRetryPolicy(params map[string]string) (RetryPolicy, error) {
+	var (
+		delay      time.Duration
+		maxRetries int
+	)
+
+	for name, value := range params {
+		var err error
+		switch name {
+		case "delay":
+			delay, err = parseRetryPolicyDuration(value)
+		case "max":
+			maxRetries, err = parseRetryPolicyMax(value)
+		default:
+			err = errors.New("unknown param")
+		}
+		if err != nil {
+			return RetryPolicy{}, fmt.Errorf("param %q: %w", name, err)
+		}
+	}
+
+	if _, ok := params["delay"]; !ok {
+		return RetryPolicy{}, errors.New(`param "delay" is required`)
+	}
+
+	return RetryPolicy{Backoff: NewConstantBackoff(delay), MaxRetries: maxRetries}, nil
+}
+
+// parseRetryPolicySpec splits policy spec into the policy kind and its params.
+func parseRetryPolicySpec(spec string) (string, map[string]string, error) {
+	spec = strings.TrimSpace(spec)
+
+	openIdx := strings.Index(spec, "(")
+	if openIdx < 0 {
+		// params are optional, so "never" is the same as "never()"
+		if spec == "" {
+			return "", nil, errors.New("empty spec")
+		}
+		return spec, nil, nil
+	}
+	if !strings.HasSuffix(spec, ")") {
+		return "", nil, errors.New(`missing closing ")"`)
+	}
+
+	kind := strings.TrimSpace(spec[:openIdx])
+	if kind == "" {
+		return "", nil, errors.New("missing policy kind")
+	}
+
+	params := make(map[string]string)
+	rawParams := strings.TrimSpace(spec[openIdx+1 : len(spec)-1])
+	if rawParams == "" {
+		return kind, params, nil
+	}
+
+	for _, rawParam := range strings.Split(rawParams, ",") {
+		name, value, ok := strings.Cut(rawParam, "=")
+		name, value = strings.TrimSpace(name), strings.TrimSpace(value)
+		if !ok || name == "" || value == "" {
+			return "", nil, fmt.Errorf("malformed param %q, expected <name>=<value>", strings.TrimSpace(rawParam))
+		}
+		if _, ok := params[name]; ok {
+			return "", nil, fmt.Errorf("duplicate param %q", name)
+		}
+
+		params[name] = value
+	}
+
+	return kind, params, nil
+}
+
+func parseRetryPolicyDuration(value string) (time.Duration, error) {
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, err
+	}
+	if d < 0 {
+		return 0, errors.New("must not be negative")
+	}
+
+	return d, nil
+}
+
+func parseRetryPolicyJitter(value string) (float64, error) {
+	var (
+		jitter float64
+		err    error
+	)
+	if percents, ok := strings.CutSuffix(value, "%"); ok {
+		jitter, err = strconv.ParseFloat(strings.TrimSpace(percents), 64)
+		jitter /= 100
+	} else {
+		jitter, err = strconv.ParseFloat(value, 64)
+	}
+	if err != nil {
+		return 0, err
+	}
+	if jitter < 0 || jitter > 1 {
+		return 0, errors.New("must be in the range of [0%, 100%]")
+	}
+
+	return jitter, nil
+}
+
+func parseRetryPolicyMax(value string) (int, error) {
+	maxRetries, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, err
+	}
+	if maxRetries < 0 {
+		return 0, errors.New("must not be negative")
+	}
+
+	return maxRetries, nil
+}
@@ -0,0 +1,167 @@
+package gue
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/vortex14/gue/v7/adapter"
+	adapterTesting "github.com/vortex14/gue/v7/adapter/testing"
+)
+
+func TestParseRetryPolicy_Errors(t *testing.T) {
+	for name, spec := range map[string]string{
+		"empty":                 "",
+		"blank":                 "   ",
+		"unknown kind":          "linear(base=1s)",
+		"missing kind":          "(base=1s)",
+		"missing closing paren": "exponential(base=1s",
+		"malformed param":       "exponential(base)",
+		"empty param value":     "exponential(base=)",
+		"empty param name":      "exponential(=1s)",
+		"trailing comma":        "exponential(base=1s,)",
+		"duplicate param":       "exponential(base=1s, base=2s)",
+		"unknown param":         "exponential(foo=1s)",
+		"invalid duration":      "exponential(base=10)",
+		"negative duration":     "exponential(base=-1s)",
+		"cap less than base":    "exponential(base=1m, cap=1s)",
+		"invalid multiplier":    "exponential(multiplier=x)",
+		"small multiplier":      "exponential(multiplier=0.5)",
+		"invalid jitter":        "exponential(jitter=x%)",
+		"too big jitter":        "exponential(jitter=120%)",
+		"negative jitter":       "exponential(jitter=-0.1)",
+		"invalid max":           "exponential(max=1.5)",
+		"negative max":          "constant(delay=1s, max=-1)",
+		"missing delay":         "constant(max=3)",
+		"never with params":     "never(max=3)",
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := ParseRetryPolicy(spec)
+			require.Error(t, err)
+			assert.ErrorIs(t, err, ErrInvalidRetryPolicy)
+			assert.Contains(t, err.Error(), spec)
+		})
+	}
+}
+
+func TestParseRetryPolicy(t *testing.T) {
+	t.Run("exponential", func(t *testing.T) {
+		policy, err := ParseRetryPolicy(" exponential( base=10s, cap=1h, jitter=20%, max=15 ) ")
+		require.NoError(t, err)
+		assert.Equal(t, 15, policy.MaxRetries)
+
+		b := policy.backoff(BackoffNever)
+		assert.InDelta(t, 10*time.Second, b(0), float64(2*time.Second))
+		assert.InDelta(t, time.Hour, b(15), float64(12*time.Minute))
+		assert.Equal(t, time.Duration(-1), b(16))
+	})
+
+	t.Run("exponential defaults", func(t *testing.T) {
+		policy, err := ParseRetryPolicy("exponential()")
+		require.NoError(t, err)
+		assert.Equal(t, 0, policy.MaxRetries)
+
+		b := policy.backoff(BackoffNever)
+		assert.InDelta(t, time.Second, b(0), float64(200*time.Millisecond))
+		assert.InDelta(t, time.Hour, b(100), float64(12*time.Minute))
+	})
+
+	t.Run("exponential no jitter", func(t *testing.T) {
+		policy, err := ParseRetryPolicy("exponential(base=1s, multiplier=2, jitter=0)")
+		require.NoError(t, err)
+
+		b := policy.backoff(BackoffNever)
+		assert.Equal(t, time.Second, b(0))
+		assert.Equal(t, 2*time.Second, b(1))
+		assert.Equal(t, 4*time.Second, b(2))
+	})
+
+	t.Run("constant", func(t *testing.T) {
+		policy, err := ParseRetryPolicy("constant(delay=30s, max=2)")
+		require.NoError(t, err)
+
+		b := policy.backoff(BackoffNever)
+		assert.Equal(t, 30*time.Second, b(1))
+		assert.Equal(t, 30*time.Second, b(2))
+		assert.Equal(t, time.Duration(-1), b(3))
+	})
+
+	t.Run("never", func(t *testing.T) {
+		for _, spec := range []string{"never", "never()"} {
+			policy, err := ParseRetryPolicy(spec)
+			require.NoError(t, err)
+			assert.Equal(t, time.Duration(-1), policy.backoff(DefaultExponentialBackoff)(1))
+		}
+	})
+
+	t.Run("max retries with fallback backoff", func(t *testing.T) {
+		policy := RetryPolicy{MaxRetries: 1}
+
+		b := policy.backoff(NewConstantBackoff(time.Minute))
+		assert.Equal(t, time.Minute, b(1))
+		assert.Equal(t, time.Duration(-1), b(2))
+	})
+}
+
+func TestWorker_TypeRetryPolicy(t *testing.T) {
+	for name, openFunc := range adapterTesting.AllAdaptersOpenTestPool {
+		t.Run(name, func(t *testing.T) {
+			testWorkerTypeRetryPolicy(t, openFunc(t))
+		})
+	}
+}
+
+func testWorkerTypeRetryPolicy(t *testing.T, connPool adapter.ConnPool) {
+	ctx := context.Background()
+
+	c, err := NewClient(connPool, WithClientBackoff(NewConstantBackoff(0)))
+	require.NoError(t, err)
+
+	policy, err := ParseRetryPolicy("constant(delay=0s, max=1)")
+	require.NoError(t, err)
+
+	wm := WorkMap{
+		"policy-job": func(ctx context.Context, j *Job) error {
+			return errors.New("policy job failed")
+		},
+		"default-job": func(ctx context.Context, j *Job) error {
+			return errors.New("default job failed")
+		},
+	}
+
+	policies := map[string]RetryPolicy{"policy-job": policy}
+	wPolicy, err := NewWorker(c, wm, WithWorkerQueue("policy"), WithWorkerTypeRetryPolicy(policies))
+	require.NoError(t, err)
+	wDefault, err := NewWorker(c, wm, WithWorkerQueue("default"), WithWorkerTypeRetryPolicy(policies))
+	require.NoError(t, err)
+
+	policyJob := Job{Type: "policy-job", Queue: "policy"}
+	require.NoError(t, c.Enqueue(ctx, &policyJob))
+	defaultJob := Job{Type: "default-job", Queue: "default"}
+	require.NoError(t, c.Enqueue(ctx, &defaultJob))
+
+	// every job is worked and errored twice, policy job is discarded after the max retries
+	for i := 0; i < 2; i++ {
+		assert.True(t, wPolicy.WorkOne(ctx))
+		assert.True(t, wDefault.WorkOne(ctx))
+	}
+
+	j, err := c.LockJobByID(ctx, policyJob.ID)
+	require.ErrorIs(t, err, adapter.ErrNoRows)
+	assert.Nil(t, j)
+
+	j, err = c.LockJobByID(ctx, defaultJob.ID)
+	require.NoError(t, err)
+	require.NotNil(t, j)
+
+	t.Cleanup(func() {
+		err := j.Done(ctx)
+		assert.NoError(t, err)
+	})
+
+	assert.Equal(t, int32(2), j.ErrorCount)
+}
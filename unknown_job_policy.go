@@ -0,0 +1,36 @@
+package gue
+
+type unknownJobAction int
+
+const (
+	unknownJobRetry unknownJobAction = iota
+	unknownJobDiscard
+	unknownJobDeadLetter
+)
+
+// UnknownJobPolicy defines what happens to the job which type is not registered in the WorkMap,
+// see WithWorkerUnknownJobPolicy.
+type UnknownJobPolicy struct {
+	action unknownJobAction
+	queue  string
+}
+
+// UnknownJobRetry builds UnknownJobPolicy that marks the job as errored, so it is retried with the worker backoff.
+// This is the default policy.
+func UnknownJobRetry() UnknownJobPolicy {
+	return UnknownJobPolicy{action: unknownJobRetry}
+}
+
+// UnknownJobDiscard builds UnknownJobPolicy that deletes the job. The unknown job type error is stored as the job
+// last error in the same transaction right before the job is deleted, so it is visible to the delete triggers
+// if there are any, and it is passed to the hooks set with WithWorkerHooksUnknownJobType.
+func UnknownJobDiscard() UnknownJobPolicy {
+	return UnknownJobPolicy{action: unknownJobDiscard}
+}
+
+// UnknownJobDeadLetter builds UnknownJobPolicy that moves the job to the given queue, so it is not retried
+// in the original one, but can be inspected and re-enqueued later. The job is moved with the error count increased
+// and the last error set to the unknown job type error, the same way Job.Error does.
+func UnknownJobDeadLetter(queue string) UnknownJobPolicy {
+	return UnknownJobPolicy{action: unknownJobDeadLetter, queue: queue}
+}
@@ -0,0 +1,186 @@
+package gue
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/oklog/ulid/v2"
+
+	"github.com/vortex14/gue/v7/adapter"
+)
+
+const (
+	defaultWebhookDeliveryIDHeader = "X-Delivery-ID"
+	defaultWebhookMaxBodySize      = 1 << 20
+)
+
+// ErrWebhookInvalidSignature is returned by WebhookVerifier implementations when the request signature is missing
+// or does not match the expected one.
+var ErrWebhookInvalidSignature = errors.New("invalid webhook signature")
+
+// WebhookMapper maps incoming webhook request to the Job that will be enqueued. Request body is already read,
+// so use the body parameter instead of the request one. Returned error rejects the webhook with
+// the 422 Unprocessable Entity status.
+type WebhookMapper func(r *http.Request, body []byte) (*Job, error)
+
+// WebhookVerifier verifies incoming webhook request authenticity, e.g. checks its signature.
+// Returned error rejects the webhook with the 401 Unauthorized status.
+type WebhookVerifier func(r *http.Request, body []byte) error
+
+// WebhookOption defines a type that allows to set webhook handler properties during the build-time.
+type WebhookOption func(*webhookHandler)
+
+// WithWebhookVerifier sets the verifier for incoming webhook requests. Requests are not verified by default.
+func WithWebhookVerifier(verifier WebhookVerifier) WebhookOption {
+	return func(h *webhookHandler) {
+		h.verifier = verifier
+	}
+}
+
+// WithWebhookDeliveryIDHeader overrides default "X-Delivery-ID" header name that holds webhook delivery ID.
+func WithWebhookDeliveryIDHeader(header string) WebhookOption {
+	return func(h *webhookHandler) {
+		h.deliveryIDHeader = header
+	}
+}
+
+// WithWebhookMaxBodySize overrides default max webhook request body size of 1 MiB.
+func WithWebhookMaxBodySize(size int64) WebhookOption {
+	return func(h *webhookHandler) {
+		h.maxBodySize = size
+	}
+}
+
+// NewHMACSHA256Verifier builds WebhookVerifier that checks hex-encoded HMAC-SHA256 signature of the request body
+// passed in the header. Optional prefix is trimmed from the header value before the check, e.g. "sha256=".
+func NewHMACSHA256Verifier(secret []byte, header, prefix string) WebhookVerifier {
+	return func(r *http.Request, body []byte) error {
+		signature, err := hex.DecodeString(strings.TrimPrefix(r.Header.Get(header), prefix))
+		if err != nil || len(signature) == 0 {
+			return ErrWebhookInvalidSignature
+		}
+
+		mac := hmac.New(sha256.New, secret)
+		mac.Write(body)
+		if !hmac.Equal(signature, mac.Sum(nil)) {
+			return ErrWebhookInvalidSignature
+		}
+
+		return nil
+	}
+}
+
+type webhookHandler struct {
+	c                *Client
+	mapper           WebhookMapper
+	verifier         WebhookVerifier
+	deliveryIDHeader string
+	maxBodySize      int64
+}
+
+// WebhookHandler returns http.Handler that turns incoming webhooks into jobs. Every request is verified (if the
+// verifier is set), mapped to the Job using mapper and enqueued with the ID derived from the webhook delivery ID,
+// so the same delivery is enqueued only once while the job exists in the queue. Successfully enqueued job ID is
+// returned in the 202 Accepted response, repeated delivery gets 409 Conflict.
+//
+// Please note that ID derived from the delivery ID is not time-sortable as regular job IDs are. For the same reason
+// the job is never split by the splitter set with WithClientSplitter, as EnqueueWithID does not split jobs as well -
+// split jobs would get new IDs and repeated delivery could not be detected anymore.
+func WebhookHandler(c *Client, mapper WebhookMapper, options ...WebhookOption) http.Handler {
+	h := webhookHandler{
+		c:                c,
+		mapper:           mapper,
+		deliveryIDHeader: defaultWebhookDeliveryIDHeader,
+		maxBodySize:      defaultWebhookMaxBodySize,
+	}
+
+	for _, option := range options {
+		option(&h)
+	}
+
+	return &h
+}
+
+// ServeHTTP implements http.Handler.ServeHTTP()
+func (h *webhookHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeWebhookResponse(w, http.StatusMethodNotAllowed, "", "method not allowed")
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, h.maxBodySize+1))
+	if err != nil {
+		writeWebhookResponse(w, http.StatusBadRequest, "", "could not read request body")
+		return
+	}
+	if int64(len(body)) > h.maxBodySize {
+		writeWebhookResponse(w, http.StatusRequestEntityTooLarge, "", "request body is too large")
+		return
+	}
+
+	if h.verifier != nil {
+		if err := h.verifier(r, body); err != nil {
+			writeWebhookResponse(w, http.StatusUnauthorized, "", err.Error())
+			return
+		}
+	}
+
+	deliveryID := r.Header.Get(h.deliveryIDHeader)
+	if deliveryID == "" {
+		writeWebhookResponse(w, http.StatusBadRequest, "", fmt.Sprintf("missing %s header", h.deliveryIDHeader))
+		return
+	}
+
+	j, err := h.mapper(r, body)
+	if err != nil {
+		writeWebhookResponse(w, http.StatusUnprocessableEntity, "", err.Error())
+		return
+	}
+	if j == nil || j.Type == "" {
+		writeWebhookResponse(w, http.StatusUnprocessableEntity, "", ErrMissingType.Error())
+		return
+	}
+
+	jobID := webhookJobID(deliveryID)
+	enqueued, err := h.c.execEnqueueIfNotExists(r.Context(), j, h.c.pool, jobID)
+	if err != nil {
+		h.c.logger.Error("Could not enqueue webhook job", adapter.Err(err), adapter.F("delivery-id", deliveryID))
+		writeWebhookResponse(w, http.StatusInternalServerError, "", "could not enqueue job")
+		return
+	}
+	if !enqueued {
+		writeWebhookResponse(w, http.StatusConflict, jobID.String(), "duplicate delivery")
+		return
+	}
+
+	writeWebhookResponse(w, http.StatusAccepted, jobID.String(), "")
+}
+
+// webhookJobID derives stable job ID from the webhook delivery ID.
+func webhookJobID(deliveryID string) ulid.ULID {
+	hash := sha256.Sum256([]byte(deliveryID))
+
+	var id ulid.ULID
+	copy(id[:], hash[:len(id)])
+
+	return id
+}
+
+type webhookResponse struct {
+	JobID string `json:"job_id,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+func writeWebhookResponse(w http.ResponseWriter, status int, jobID, errMsg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(webhookResponse{JobID: jobID, Error: errMsg})
+}
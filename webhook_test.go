@@ -0,0 +1,207 @@
+package gue
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/vortex14/gue/v7/adapter"
+	adapterTesting "github.com/vortex14/gue/v7/adapter/testing"
+)
+
+const testWebhookSecret = "top-secret"
+
+func signTestWebhook(body string) string {
+	mac := hmac.New(sha256.New, []byte(testWebhookSecret))
+	mac.Write([]byte(body))
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func newTestWebhookRequest(body, deliveryID, signature string) *http.Request {
+	r := httptest.NewRequest(http.MethodPost, "/webhooks/orders", strings.NewReader(body))
+	if deliveryID != "" {
+		r.Header.Set("X-Delivery-ID", deliveryID)
+	}
+	if signature != "" {
+		r.Header.Set("X-Signature", signature)
+	}
+	return r
+}
+
+func decodeTestWebhookResponse(t *testing.T, w *httptest.ResponseRecorder) webhookResponse {
+	t.Helper()
+
+	var resp webhookResponse
+	err := json.NewDecoder(w.Body).Decode(&resp)
+	require.NoError(t, err)
+
+	return resp
+}
+
+var testWebhookMapper = WebhookMapper(func(r *http.Request, body []byte) (*Job, error) {
+	if !json.Valid(body) {
+		return nil, errors.New("body is not a valid JSON")
+	}
+
+	return &Job{Type: "order-created", Queue: "webhooks", Args: body}, nil
+})
+
+func TestWebhookHandler_Rejects(t *testing.T) {
+	c, err := NewClient(nil)
+	require.NoError(t, err)
+
+	h := WebhookHandler(
+		c,
+		testWebhookMapper,
+		WithWebhookVerifier(NewHMACSHA256Verifier([]byte(testWebhookSecret), "X-Signature", "sha256=")),
+		WithWebhookMaxBodySize(64),
+	)
+
+	for name, tc := range map[string]struct {
+		r      *http.Request
+		status int
+	}{
+		"wrong method": {
+			r:      httptest.NewRequest(http.MethodGet, "/webhooks/orders", nil),
+			status: http.StatusMethodNotAllowed,
+		},
+		"missing signature": {
+			r:      newTestWebhookRequest(`{"id":1}`, "delivery-1", ""),
+			status: http.StatusUnauthorized,
+		},
+		"malformed signature": {
+			r:      newTestWebhookRequest(`{"id":1}`, "delivery-1", "sha256=not-a-hex"),
+			status: http.StatusUnauthorized,
+		},
+		"wrong signature": {
+			r:      newTestWebhookRequest(`{"id":1}`, "delivery-1", signTestWebhook(`{"id":2}`)),
+			status: http.StatusUnauthorized,
+		},
+		"too large body": {
+			r:      newTestWebhookRequest(strings.Repeat("a", 65), "delivery-1", signTestWebhook(strings.Repeat("a", 65))),
+			status: http.StatusRequestEntityTooLarge,
+		},
+		"missing delivery id": {
+			r:      newTestWebhookRequest(`{"id":1}`, "", signTestWebhook(`{"id":1}`)),
+			status: http.StatusBadRequest,
+		},
+		"mapper rejection": {
+			r:      newTestWebhookRequest(`{invalid]json>`, "delivery-1", signTestWebhook(`{invalid]json>`)),
+			status: http.StatusUnprocessableEntity,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, tc.r)
+
+			assert.Equal(t, tc.status, w.Code)
+
+			resp := decodeTestWebhookResponse(t, w)
+			assert.Empty(t, resp.JobID)
+			assert.NotEmpty(t, resp.Error)
+		})
+	}
+}
+
+func TestWebhookJobID(t *testing.T) {
+	assert.Equal(t, webhookJobID("delivery-1"), webhookJobID("delivery-1"))
+	assert.NotEqual(t, webhookJobID("delivery-1"), webhookJobID("delivery-2"))
+}
+
+func TestWebhookHandler_SplitterIsNotApplied(t *testing.T) {
+	body := `{"order_ids":[1,2,3,4,5,6,7,8,9,10]}`
+
+	ct := new(adapterTesting.CommandTag)
+	ct.On("RowsAffected").Return(int64(1))
+
+	connPool := new(adapterTesting.ConnPool)
+	connPool.Queryable.On("Exec", mock.Anything, mock.Anything, mock.MatchedBy(func(args []any) bool {
+		return args[0] == webhookJobID("delivery-1").String() && string(args[5].([]byte)) == body
+	})).Return(ct, nil).Once()
+
+	splitterCalled := false
+	c, err := NewClient(connPool, WithClientSplitter("order-created", func(args []byte) ([][]byte, error) {
+		splitterCalled = true
+		return [][]byte{args[:len(args)/2], args[len(args)/2:]}, nil
+	}, 8))
+	require.NoError(t, err)
+
+	h := WebhookHandler(c, testWebhookMapper)
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, newTestWebhookRequest(body, "delivery-1", ""))
+	require.Equal(t, http.StatusAccepted, w.Code)
+
+	// oversized webhook job is enqueued as is with the ID derived from the delivery ID, otherwise repeated delivery
+	// could not be detected
+	assert.False(t, splitterCalled)
+	assert.Equal(t, webhookJobID("delivery-1").String(), decodeTestWebhookResponse(t, w).JobID)
+	connPool.Queryable.AssertExpectations(t)
+}
+
+func TestWebhookHandler(t *testing.T) {
+	for name, openFunc := range adapterTesting.AllAdaptersOpenTestPool {
+		t.Run(name, func(t *testing.T) {
+			testWebhookHandler(t, openFunc(t))
+		})
+	}
+}
+
+func testWebhookHandler(t *testing.T, connPool adapter.ConnPool) {
+	ctx := context.Background()
+
+	c, err := NewClient(connPool)
+	require.NoError(t, err)
+
+	h := WebhookHandler(
+		c,
+		testWebhookMapper,
+		WithWebhookVerifier(NewHMACSHA256Verifier([]byte(testWebhookSecret), "X-Signature", "sha256=")),
+	)
+
+	body := `{"order_id":42}`
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, newTestWebhookRequest(body, "delivery-1", signTestWebhook(body)))
+	require.Equal(t, http.StatusAccepted, w.Code)
+
+	resp := decodeTestWebhookResponse(t, w)
+	require.NotEmpty(t, resp.JobID)
+	assert.Empty(t, resp.Error)
+
+	// the same delivery is rejected as a duplicate and points to the already enqueued job
+	wDuplicate := httptest.NewRecorder()
+	h.ServeHTTP(wDuplicate, newTestWebhookRequest(body, "delivery-1", signTestWebhook(body)))
+	require.Equal(t, http.StatusConflict, wDuplicate.Code)
+
+	respDuplicate := decodeTestWebhookResponse(t, wDuplicate)
+	assert.Equal(t, resp.JobID, respDuplicate.JobID)
+
+	j, err := c.LockJob(ctx, "webhooks")
+	require.NoError(t, err)
+	require.NotNil(t, j)
+
+	t.Cleanup(func() {
+		err := j.Done(ctx)
+		assert.NoError(t, err)
+	})
+
+	assert.Equal(t, resp.JobID, j.ID.String())
+	assert.Equal(t, "order-created", j.Type)
+	assert.Equal(t, []byte(body), j.Args)
+
+	j2, err := c.LockJob(ctx, "webhooks")
+	require.NoError(t, err)
+	assert.Nil(t, j2)
+}
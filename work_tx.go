@@ -0,0 +1,61 @@
+package gue
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/vortex14/gue/v7/adapter"
+)
+
+const workTxSavepoint = "gue_work_tx"
+
+// WorkFuncTx is the handler function that performs the Job within the job transaction. Transaction is the one
+// the job is locked in, so everything the handler does in tx is committed atomically with the job completion:
+// either the handler changes are persisted and the job is deleted, or neither of them is. If the handler returns
+// an error or panics, its changes are rolled back and the job is marked as errored the same way as for WorkFunc.
+// Jobs chained with Job.EnqueueNext are inserted in the same transaction as well.
+//
+// Use WorkFuncTx only for the handlers whose side effects are the writes to the same database the jobs are
+// stored in. Side effects outside the transaction, e.g. HTTP calls or sending emails, are not rolled back
+// and may still happen several times, so such handlers should use WorkFunc and be idempotent instead.
+//
+// Handler must not commit or roll back tx, the worker takes care of it.
+type WorkFuncTx func(ctx context.Context, tx adapter.Tx, j *Job) error
+
+// WorkMapTx is a map of Job names to WorkFuncTx that are used to perform Jobs of a given type within the job
+// transaction, see WithWorkerWorkMapTx.
+type WorkMapTx map[string]WorkFuncTx
+
+// workInJobTx builds WorkFunc that calls wf within the savepoint of the job transaction, so the handler changes
+// are rolled back without releasing the job lock if the handler fails.
+func workInJobTx(ctx context.Context, wf WorkFuncTx) WorkFunc {
+	return func(handlerCtx context.Context, j *Job) (err error) {
+		tx := j.Tx()
+		if tx == nil {
+			return ErrJobNotLocked
+		}
+
+		if _, err := tx.Exec(ctx, "SAVEPOINT "+workTxSavepoint); err != nil {
+			return fmt.Errorf("could not start job handler transaction: %w", err)
+		}
+
+		succeeded := false
+		// handler changes are rolled back on error and on panic, so they are not committed with the errored job
+		defer func() {
+			if succeeded {
+				return
+			}
+
+			if _, rbErr := tx.Exec(ctx, "ROLLBACK TO SAVEPOINT "+workTxSavepoint); rbErr != nil && err != nil {
+				err = fmt.Errorf("%w (could not roll back job handler transaction: %v)", err, rbErr)
+			}
+		}()
+
+		if err := wf(handlerCtx, tx, j); err != nil {
+			return err
+		}
+
+		succeeded = true
+		return nil
+	}
+}
@@ -5,16 +5,21 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"math/rand"
 	"runtime"
+	"sort"
 	"sync"
 	"time"
 
 	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
 	"go.opentelemetry.io/otel/metric"
 	noopM "go.opentelemetry.io/otel/metric/noop"
+	"go.opentelemetry.io/otel/propagation"
 	"go.opentelemetry.io/otel/trace"
 	noopT "go.opentelemetry.io/otel/trace/noop"
 	"golang.org/x/sync/errgroup"
+	"golang.org/x/time/rate"
 
 	"github.com/vortex14/gue/v7/adapter"
 )
@@ -27,6 +32,9 @@ const (
 	defaultQueueName    = ""
 
 	defaultPanicStackBufSize = 1024
+	defaultDrainEmptyPolls   = 1
+	defaultPollJitter        = 0.1
+	maxPollJitter            = 0.5
 
 	// PriorityPollStrategy cares about the priority first to lock top priority jobs first even if there are available
 	// ones that should be executed earlier but with lower priority.
@@ -38,12 +46,26 @@ const (
 
 // WorkFunc is the handler function that performs the Job. If an error is returned, the Job
 // is either re-enqueued with the given backoff or is discarded based on the worker backoff strategy
-// and returned error.
+// and returned error. Errors built with ErrPostponeJobIn or ErrPostponeJobAt take precedence over the generic error
+// handling and only change the time the Job will run at, without marking it as failed.
 //
 // Modifying Job fields and calling any methods that are modifying its state within the handler may lead to undefined
 // behaviour. Please never do this.
 type WorkFunc func(ctx context.Context, j *Job) error
 
+// Handler builds WorkFunc that decodes JSON-encoded job Args into the value of type T before calling fn.
+// Job with the Args that can not be decoded is discarded, as retrying it will never succeed.
+func Handler[T any](fn func(ctx context.Context, j *Job, args T) error) WorkFunc {
+	return func(ctx context.Context, j *Job) error {
+		var args T
+		if err := j.UnmarshalArgs(&args); err != nil {
+			return ErrDiscardJob(err.Error())
+		}
+
+		return fn(ctx, j, args)
+	}
+}
+
 // HookFunc is a function that may react to a Job lifecycle events. All the callbacks are being executed synchronously,
 // so be careful with the long-running locking operations. Hooks do not return an error, therefore they can not and
 // must not be used to affect the Job execution flow, e.g. cancel it - this is the WorkFunc responsibility.
@@ -53,6 +75,18 @@ type WorkFunc func(ctx context.Context, j *Job) error
 // Depending on the event err parameter may be empty or not - check the event description for its meaning.
 type HookFunc func(ctx context.Context, j *Job, err error)
 
+// WorkerStatus is the current state of the Worker or WorkerPool.
+type WorkerStatus string
+
+const (
+	// WorkerStatusStopped means that the worker is not running.
+	WorkerStatusStopped WorkerStatus = "stopped"
+	// WorkerStatusRunning means that the worker is running and working jobs.
+	WorkerStatusRunning WorkerStatus = "running"
+	// WorkerStatusPaused means that the worker is running, but does not lock new jobs until it is resumed.
+	WorkerStatusPaused WorkerStatus = "paused"
+)
+
 // WorkMap is a map of Job names to WorkFuncs that are used to perform Jobs of a
 // given type.
 type WorkMap map[string]WorkFunc
@@ -60,20 +94,37 @@ type WorkMap map[string]WorkFunc
 // pollFunc is a function that queries the DB for the next job to work on
 type pollFunc func(context.Context, string) (*Job, error)
 
+// JobLocker locks the next Job to be worked from the queue, see Client.LockJob for the contract. Client implements
+// JobLocker, custom implementation set with WithWorkerJobLocker allows to run the Worker without the DB, e.g. in tests
+// using guetest.Locker.
+type JobLocker interface {
+	LockJob(ctx context.Context, queue string) (*Job, error)
+}
+
+var _ JobLocker = (*Client)(nil)
+
 // Worker is a single worker that pulls jobs off the specified queue. If no Job
 // is found, the Worker will sleep for interval seconds.
 type Worker struct {
 	wm           WorkMap
 	interval     time.Duration
+	pollJitter   float64
 	queue        string
 	c            *Client
 	id           string
 	logger       adapter.Logger
 	mu           sync.Mutex
 	running      bool
+	paused       bool
+	resumed      chan struct{}
 	pollStrategy PollStrategy
 	pollFunc     pollFunc
+	locker       JobLocker
 	jobTTL       time.Duration
+	minPriority  JobPriority
+
+	maxIdleInterval      time.Duration
+	maxLockErrorInterval time.Duration
 
 	graceful    bool
 	gracefulCtx func() context.Context
@@ -81,18 +132,58 @@ type Worker struct {
 	tracer trace.Tracer
 	meter  metric.Meter
 
+	tracePropagator propagation.TextMapPropagator
+
+	wmTx WorkMapTx
+
 	unknownJobTypeWF WorkFunc
+	unknownJobPolicy UnknownJobPolicy
+
+	limiter *rate.Limiter
+
+	retryPolicies map[string]RetryPolicy
+
+	typeLimiters    map[string]Limiter
+	limitedJobDelay time.Duration
+
+	dedupKey    DedupKeyFunc
+	dedupKeySet DedupKeySet
+	dedupDelay  time.Duration
+
+	heartbeatInterval time.Duration
+	batchSize         int
+
+	draining        bool
+	drainEmptyPolls int
 
 	hooksJobLocked      []HookFunc
 	hooksUnknownJobType []HookFunc
 	hooksJobDone        []HookFunc
 	hooksJobUndone      []HookFunc
 
+	eventSink JobEventSink
+
+	onIdle func()
+	onBusy func()
+	busy   bool
+	// idleChanged is set by the pool to track the state of all its workers
+	idleChanged func(idle bool)
+
+	// number of consecutive failed attempts to lock a job
+	lockErrors int
+
+	stallThreshold time.Duration
+	healthMu       sync.Mutex
+	health         workerHealth
+
 	mWorked   metric.Int64Counter
 	mDuration metric.Int64Histogram
 
 	panicStackBufSize int
 	spanWorkOneNoJob  bool
+
+	// staggerStart delays the first poll by the random poll jitter, it is set for the pool workers
+	staggerStart bool
 }
 
 // NewWorker returns a Worker that fetches Jobs from the Client and executes
@@ -116,17 +207,25 @@ func NewWorker(c *Client, wm WorkMap, options ...WorkerOption) (*Worker, error)
 		meter:        noopM.NewMeterProvider().Meter("noop"),
 
 		panicStackBufSize: defaultPanicStackBufSize,
+		drainEmptyPolls:   defaultDrainEmptyPolls,
+		limitedJobDelay:   defaultLimitedJobDelay,
+		dedupDelay:        defaultDedupDelay,
+		pollJitter:        defaultPollJitter,
+		minPriority:       JobPriorityLowest,
 	}
 
 	for _, option := range options {
 		option(&w)
 	}
 
-	switch w.pollStrategy {
-	case RunAtPollStrategy:
-		w.pollFunc = w.c.LockNextScheduledJob
-	default:
-		w.pollFunc = w.c.LockJob
+	if w.locker != nil {
+		w.pollFunc = w.locker.LockJob
+	}
+	if w.dedupKey != nil && w.dedupKeySet == nil {
+		w.dedupKeySet = NewDedupKeySet()
+	}
+	if w.pollFunc == nil {
+		w.pollFunc = w.defaultPollFunc()
 	}
 
 	w.logger = w.logger.With(adapter.F("worker-id", w.id))
@@ -134,6 +233,27 @@ func NewWorker(c *Client, wm WorkMap, options ...WorkerOption) (*Worker, error)
 	return &w, w.initMetrics()
 }
 
+// defaultPollFunc picks the Client lock function for the worker poll strategy and min priority.
+func (w *Worker) defaultPollFunc() pollFunc {
+	if w.minPriority < JobPriorityLowest {
+		if w.pollStrategy == RunAtPollStrategy {
+			return func(ctx context.Context, queue string) (*Job, error) {
+				return w.c.lockNextScheduledJobMinPriority(ctx, queue, w.minPriority)
+			}
+		}
+
+		return func(ctx context.Context, queue string) (*Job, error) {
+			return w.c.lockJobMinPriority(ctx, queue, w.minPriority)
+		}
+	}
+
+	if w.pollStrategy == RunAtPollStrategy {
+		return w.c.LockNextScheduledJob
+	}
+
+	return w.c.LockJob
+}
+
 // Run pulls jobs off the Worker's queue at its interval. This function does
 // not run in its own goroutine, so it’s possible to wait for completion. Use
 // context cancellation to shut it down.
@@ -144,22 +264,39 @@ func (w *Worker) Run(ctx context.Context) error {
 // runLoop pulls jobs off the Worker's queue at its interval.
 func (w *Worker) runLoop(ctx context.Context) error {
 	defer w.logger.Info("Worker finished")
+	// stopped worker is not busy anymore
+	defer w.trackIdle(false)
 
 	timer := time.NewTimer(w.interval)
 	defer timer.Stop()
 
+	// Stagger the first poll of the pool workers, so they do not query the DB all at once
+	if w.staggerStart {
+		timer.Reset(randomDuration(w.maxPollJitter()))
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-timer.C:
+		}
+	}
+
+	// number of consecutive polls with no jobs found since the worker started draining
+	emptyPolls := 0
+	// interval grows while the queue is empty when the idle backoff is enabled
+	interval := w.interval
+
 	for {
-		handlerCtx := ctx
-		if w.graceful {
-			if w.gracefulCtx == nil {
-				handlerCtx = context.Background()
-			} else {
-				handlerCtx = w.gracefulCtx()
-			}
+		// Block while paused, but still react to the shutdown
+		if !w.waitResumed(ctx) {
+			return nil
 		}
 
 		// Try to work a job
-		if w.WorkOne(handlerCtx) {
+		if w.Step(ctx) {
+			emptyPolls = 0
+			interval = w.interval
+
 			// Since we just did work, non-blocking check whether we should exit
 			select {
 			case <-ctx.Done():
@@ -169,9 +306,28 @@ func (w *Worker) runLoop(ctx context.Context) error {
 			}
 		}
 
+		if w.isDraining() {
+			emptyPolls++
+			if emptyPolls >= w.drainEmptyPolls {
+				w.logger.Info("Worker drained the queue")
+				w.mu.Lock()
+				w.draining = false
+				w.mu.Unlock()
+				return nil
+			}
+		}
+
 		// Reset or create the timer; time.After is leaky
 		// on context cancellation since we can’t stop it.
-		timer.Reset(w.interval)
+		// Jitter prevents the workers from polling the DB in sync.
+		wait := interval
+		if w.lockErrors > 0 {
+			// Failing to lock the job is most likely the DB issue, so back off without touching the idle interval
+			wait = w.lockErrorInterval(w.lockErrors)
+		} else {
+			interval = w.nextIdleInterval(interval)
+		}
+		timer.Reset(wait + randomDuration(w.pollJitterFor(wait)))
 
 		// No work found, block until exit or timer expires
 		select {
@@ -183,8 +339,222 @@ func (w *Worker) runLoop(ctx context.Context) error {
 	}
 }
 
+// maxPollJitter returns the max random delay added to the poll interval.
+func (w *Worker) maxPollJitter() time.Duration {
+	return w.pollJitterFor(w.interval)
+}
+
+// pollJitterFor returns the max random delay added to the given poll interval.
+func (w *Worker) pollJitterFor(interval time.Duration) time.Duration {
+	return time.Duration(w.pollJitter * float64(interval))
+}
+
+// nextIdleInterval returns the poll interval to be used after one more poll that found no jobs. With idle backoff
+// enabled the interval doubles up to the max idle interval, otherwise it is always the worker poll interval.
+func (w *Worker) nextIdleInterval(interval time.Duration) time.Duration {
+	if w.maxIdleInterval <= w.interval {
+		return w.interval
+	}
+
+	if interval >= w.maxIdleInterval/2 {
+		return w.maxIdleInterval
+	}
+
+	return interval * 2
+}
+
+// lockErrorInterval returns the poll interval to be used after the given number of consecutive failed attempts
+// to lock a job. With loop backoff enabled the interval doubles with every failed attempt up to the max lock error
+// interval, otherwise it is always the worker poll interval.
+func (w *Worker) lockErrorInterval(lockErrors int) time.Duration {
+	if w.maxLockErrorInterval <= w.interval {
+		return w.interval
+	}
+
+	interval := w.interval
+	for i := 1; i < lockErrors; i++ {
+		if interval >= w.maxLockErrorInterval/2 {
+			return w.maxLockErrorInterval
+		}
+		interval *= 2
+	}
+
+	return interval
+}
+
+// randomDuration returns random duration in the range of [0, maxD).
+func randomDuration(maxD time.Duration) time.Duration {
+	if maxD <= 0 {
+		return 0
+	}
+
+	return time.Duration(rand.Int63n(int64(maxD)))
+}
+
+// Pause makes the running Worker stop locking new jobs after it finishes the current one, until it is resumed.
+// Paused Worker still reacts to the context cancellation. Pause is safe to be called concurrently and several times,
+// Worker that is paused before it is run starts in the paused state.
+func (w *Worker) Pause() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if !w.paused {
+		w.paused = true
+		w.resumed = make(chan struct{})
+	}
+}
+
+// Resume makes paused Worker continue locking and working jobs. Resume is safe to be called concurrently
+// and several times.
+func (w *Worker) Resume() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.paused {
+		w.paused = false
+		close(w.resumed)
+	}
+}
+
+// Drain makes the Worker keep working jobs until it finds no jobs in the queue for the number of consecutive polls
+// set by WithWorkerDrainEmptyPolls, and then Run returns on its own without waiting for the context cancellation.
+// Unlike the shutdown, that stops the Worker after the current job, drain mode lets the Worker finish the queue.
+// Context cancellation is still honored while draining. Worker that is drained before it is run starts
+// in the drain mode, so it works the queue until it is empty and exits.
+func (w *Worker) Drain() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.draining = true
+}
+
+func (w *Worker) isDraining() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.draining
+}
+
+// Status returns current Worker status.
+func (w *Worker) Status() WorkerStatus {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return workerStatus(w.running, w.paused)
+}
+
+// Running returns true if the Worker is running and is not paused, so it is working jobs.
+func (w *Worker) Running() bool {
+	return w.Status() == WorkerStatusRunning
+}
+
+// waitResumed blocks while the Worker is paused. Returns false if the context was cancelled while waiting.
+func (w *Worker) waitResumed(ctx context.Context) bool {
+	w.mu.Lock()
+	paused, resumed := w.paused, w.resumed
+	w.mu.Unlock()
+
+	if !paused {
+		return true
+	}
+
+	w.logger.Info("Worker paused")
+	select {
+	case <-ctx.Done():
+		return false
+	case <-resumed:
+		w.logger.Info("Worker resumed")
+		return true
+	}
+}
+
+func workerStatus(running, paused bool) WorkerStatus {
+	switch {
+	case !running:
+		return WorkerStatusStopped
+	case paused:
+		return WorkerStatusPaused
+	default:
+		return WorkerStatusRunning
+	}
+}
+
+// Step performs a single iteration of the Worker loop - tries to work one Job applying the same handler context
+// rules as Run does, e.g. graceful shutdown mode. Unlike Run, Step never sleeps when there is no Job available,
+// so it allows to drive the Worker loop externally, e.g. from tests.
+func (w *Worker) Step(ctx context.Context) (didWork bool) {
+	defer func() {
+		w.trackIdle(didWork)
+	}()
+
+	handlerCtx := ctx
+	if w.graceful {
+		if w.gracefulCtx == nil {
+			handlerCtx = context.Background()
+		} else {
+			handlerCtx = w.gracefulCtx()
+		}
+	}
+
+	// custom job locker locks jobs one by one
+	if w.batchSize > 1 && w.locker == nil {
+		return w.workBatch(handlerCtx, ctx)
+	}
+
+	return w.workOne(handlerCtx, ctx, new(JobEvent))
+}
+
+// trackIdle calls idle and busy callbacks when the worker transitions from working jobs to finding none
+// and vice versa. Worker is idle until it finds the first job.
+func (w *Worker) trackIdle(didWork bool) {
+	if w.busy == didWork {
+		return
+	}
+	w.busy = didWork
+
+	if w.idleChanged != nil {
+		w.idleChanged(!didWork)
+	}
+
+	if didWork {
+		if w.onBusy != nil {
+			w.onBusy()
+		}
+	} else if w.onIdle != nil {
+		w.onIdle()
+	}
+}
+
 // WorkOne tries to consume single message from the queue.
 func (w *Worker) WorkOne(ctx context.Context) (didWork bool) {
+	res, _ := w.WorkOneResult(ctx)
+	return res.DidWork
+}
+
+// WorkOneOutcome tries to consume single message from the queue the same way WorkOne does, but returns the explicit
+// outcome instead of the bare flag. JobOutcomeNoJob is returned when there was no job available and
+// JobOutcomeLockFailed with the lock error when the job could not be locked. For the locked job the outcome is one of
+// the JobEvent outcomes and the returned error is the error the job failed with, if any.
+func (w *Worker) WorkOneOutcome(ctx context.Context) (JobOutcome, error) {
+	res, err := w.WorkOneResult(ctx)
+	return res.Outcome, err
+}
+
+// WorkOneResult tries to consume single message from the queue the same way WorkOne does, but returns WorkResult
+// describing what happened, so the callers driving the worker loop themselves can build the observability on top
+// of it. Returned error is the same as WorkResult.Err.
+func (w *Worker) WorkOneResult(ctx context.Context) (WorkResult, error) {
+	var event JobEvent
+	didWork := w.workOne(ctx, ctx, &event)
+
+	res := newWorkResult(event, didWork)
+	return res, res.Err
+}
+
+// workOne locks and works single job. Job handler gets ctx, that may be detached from the worker context
+// in the graceful shutdown mode, while runCtx is the worker context that is cancelled when the worker stops.
+func (w *Worker) workOne(ctx, runCtx context.Context, event *JobEvent) (didWork bool) {
+	ctx = setWorkerID(ctx, w.id)
 	ctx, span := w.tracer.Start(ctx, "Worker.WorkOne")
 	// worker option is set to generate spans even when no job is found - let it be
 	if w.spanWorkOneNoJob {
@@ -193,18 +563,14 @@ func (w *Worker) WorkOne(ctx context.Context) (didWork bool) {
 
 	j, err := w.pollFunc(ctx, w.queue)
 	if err != nil {
-		span.RecordError(fmt.Errorf("woker failed to lock a job: %w", err))
-		if w.mDuration != nil {
-			w.mWorked.Add(ctx, 1, metric.WithAttributes(attrJobType.String(""), attrSuccess.Bool(false), attrCluster.String(j.Cluster)))
-		}
-		w.logger.Error("Worker failed to lock a job", adapter.Err(err))
-
-		for _, hook := range w.hooksJobLocked {
-			hook(ctx, nil, err)
-		}
+		w.handleLockError(ctx, err, span)
+		event.setOutcome(JobOutcomeLockFailed, err)
 		return
 	}
+	w.lockErrors = 0
+	w.recordLockAttempt(nil)
 	if j == nil {
+		event.setOutcome(JobOutcomeNoJob, nil)
 		return // no job was available
 	}
 
@@ -213,6 +579,101 @@ func (w *Worker) WorkOne(ctx context.Context) (didWork bool) {
 		defer span.End()
 	}
 
+	didWork = w.workJob(ctx, runCtx, j, span, event)
+	w.sendJobEvent(*event)
+	return didWork
+}
+
+// workBatch tries to lock up to batch size jobs from the queue at once and works them one by one.
+func (w *Worker) workBatch(ctx, runCtx context.Context) (didWork bool) {
+	ctx = setWorkerID(ctx, w.id)
+	ctx, span := w.tracer.Start(ctx, "Worker.WorkBatch")
+	defer span.End()
+
+	var (
+		jobs []*Job
+		err  error
+	)
+	if w.minPriority < JobPriorityLowest {
+		jobs, err = w.c.lockJobsMinPriority(ctx, w.queue, w.batchSize, w.minPriority)
+	} else {
+		jobs, err = w.c.LockJobs(ctx, w.queue, w.batchSize)
+	}
+	if err != nil {
+		w.handleLockError(ctx, err, span)
+		return
+	}
+	w.lockErrors = 0
+	w.recordLockAttempt(nil)
+	if len(jobs) == 0 {
+		return // no jobs were available
+	}
+
+	span.SetAttributes(attribute.Int("batch-size", len(jobs)))
+	events := make([]JobEvent, 0, len(jobs))
+	for i, j := range jobs {
+		if runCtx.Err() != nil {
+			w.releaseJobs(jobs[i:])
+			break
+		}
+
+		jobCtx, jobSpan := w.tracer.Start(ctx, "Worker.WorkOne")
+		// every job is worked in isolation within its own savepoint, so a job that fails or panics
+		// does not affect the others, even if it aborts the shared transaction
+		if err := j.startSavepoint(jobCtx); err != nil {
+			jobSpan.RecordError(fmt.Errorf("failed to start batch job savepoint: %w", err))
+			w.logger.Error("Got an error on starting batch job savepoint", adapter.Err(err), adapter.F("job-id", j.ID.String()))
+		}
+
+		var event JobEvent
+		if w.workJob(jobCtx, runCtx, j, jobSpan, &event) {
+			didWork = true
+		}
+		events = append(events, event)
+		jobSpan.End()
+	}
+
+	// events are sent only once the shared transaction is committed, so the outcomes are final
+	commitErr := jobs[0].batch.commitErr()
+	for _, event := range events {
+		if commitErr != nil && event.Outcome != JobOutcomeReleased {
+			event.setOutcome(JobOutcomeErrored, commitErr)
+		}
+		w.sendJobEvent(event)
+	}
+
+	return didWork
+}
+
+// releaseJobs releases the batch jobs that were not worked yet back to the queue as is, without changing their
+// error count. Background context is used, as the worker context is already cancelled at this point, but the jobs
+// worked so far must be committed.
+func (w *Worker) releaseJobs(jobs []*Job) {
+	for _, j := range jobs {
+		if err := j.Done(context.Background()); err != nil {
+			w.logger.Error("Got an error on releasing batch job", adapter.Err(err), adapter.F("job-id", j.ID.String()))
+		}
+	}
+
+	w.logger.Info("Worker is stopped, batch jobs are released back to the queue", adapter.F("released", len(jobs)))
+}
+
+func (w *Worker) handleLockError(ctx context.Context, err error, span trace.Span) {
+	w.lockErrors++
+	w.recordLockAttempt(err)
+
+	span.RecordError(fmt.Errorf("woker failed to lock a job: %w", err))
+	w.mWorked.Add(ctx, 1, metric.WithAttributes(attrJobType.String(""), attrSuccess.Bool(false), attrCluster.String("")))
+	w.logger.Error("Worker failed to lock a job", adapter.Err(err))
+
+	for _, hook := range w.hooksJobLocked {
+		hook(ctx, nil, err)
+	}
+}
+
+// workJob works the locked job and takes care of the job cleanup. Rate limiter waits on runCtx, so the worker
+// does not block on it after it was stopped, even if the handler context is not cancelled in the graceful mode.
+func (w *Worker) workJob(ctx, runCtx context.Context, j *Job, span trace.Span, event *JobEvent) (didWork bool) {
 	processingStartedAt := time.Now()
 	span.SetAttributes(
 		attribute.String("job-id", j.ID.String()),
@@ -220,10 +681,22 @@ func (w *Worker) WorkOne(ctx context.Context) (didWork bool) {
 		attribute.String("job-type", j.Type),
 	)
 
+	if w.tracePropagator != nil {
+		// job span is ended the last, after the job is marked as done and possible panic is recovered
+		ctx, span = w.startJobSpan(ctx, j, span)
+		defer span.End()
+	}
+
 	ll := w.logger.With(adapter.F("job-id", j.ID.String()), adapter.F("job-type", j.Type))
 
-	defer w.markJobDone(ctx, j, processingStartedAt, span, ll)
-	defer w.recoverPanic(ctx, j, ll)
+	*event = JobEvent{JobID: j.ID, Type: j.Type, Queue: j.Queue, WorkerID: w.id, Outcome: JobOutcomeReleased}
+
+	w.recordJob(j, processingStartedAt)
+	// job is worked until it is marked as done, even if the handler panics
+	defer w.recordJob(nil, time.Time{})
+
+	defer w.markJobDone(ctx, j, processingStartedAt, span, ll, event)
+	defer w.recoverPanic(ctx, j, ll, event)
 
 	for _, hook := range w.hooksJobLocked {
 		hook(ctx, j, nil)
@@ -231,16 +704,53 @@ func (w *Worker) WorkOne(ctx context.Context) (didWork bool) {
 
 	didWork = true
 
+	if policy, ok := w.retryPolicies[j.Type]; ok {
+		j.backoff = policy.backoff(j.backoff)
+	}
+
 	wf, ok := w.wm[j.Type]
+	inJobTx := false
+	if wfTx, okTx := w.wmTx[j.Type]; !ok && okTx {
+		wf, ok, inJobTx = workInJobTx(ctx, wfTx), true, true
+	}
 	if !ok {
 		if w.unknownJobTypeWF == nil {
-			w.handleUnknownJobType(ctx, j, span, ll)
+			errUnknownType := w.handleUnknownJobType(ctx, j, span, ll)
+			event.setOutcome(JobOutcomeUnknownType, errUnknownType)
 			return
 		}
 
 		wf = w.unknownJobTypeWF
 	}
 
+	if w.dedupKey != nil {
+		if key := w.dedupKey(j); key != "" {
+			if !w.acquireDedupKey(ctx, key, span, ll) {
+				w.postponeDuplicateJob(ctx, j, key, span, ll)
+				event.setOutcome(JobOutcomePostponed, nil)
+				return
+			}
+			// key is released even if the handler panics
+			defer w.releaseDedupKey(ctx, key, span, ll)
+		}
+	}
+
+	// type limiter is checked after the dedup key, so the token is not consumed by the duplicate job
+	// that is not going to be worked
+	if limiter, ok := w.typeLimiters[j.Type]; ok && !w.allowJob(ctx, limiter, j, span, ll) {
+		w.postponeLimitedJob(ctx, j, span, ll)
+		event.setOutcome(JobOutcomePostponed, nil)
+		return
+	}
+
+	if w.limiter != nil {
+		if err := w.limiter.Wait(runCtx); err != nil {
+			span.RecordError(fmt.Errorf("failed to wait for rate limiter: %w", err))
+			ll.Info("Could not get rate limiter permit, releasing the job", adapter.Err(err))
+			return
+		}
+	}
+
 	handlerCtx := ctx
 	cancel := context.CancelFunc(func() {})
 	if w.jobTTL > 0 {
@@ -248,8 +758,29 @@ func (w *Worker) WorkOne(ctx context.Context) (didWork bool) {
 	}
 	defer cancel()
 
-	if err = wf(handlerCtx, j); err != nil {
+	stopHeartbeat := func() {}
+	// handler working in the job transaction uses its connection all the time, so the heartbeat can not share it,
+	// and the job row lock is held by the transaction anyway
+	if !inJobTx {
+		stopHeartbeat = w.startHeartbeat(ctx, j, ll)
+	}
+	// ensure heartbeat is stopped even if the handler panics
+	defer stopHeartbeat()
+
+	err := wf(handlerCtx, j)
+	stopHeartbeat()
+
+	if err != nil {
+		var errPostpone errJobPostpone
+		if errors.As(err, &errPostpone) {
+			w.postponeJob(ctx, j, err, errPostpone, span, ll)
+			event.setOutcome(JobOutcomePostponed, nil)
+			return
+		}
+
 		w.mWorked.Add(ctx, 1, metric.WithAttributes(attrJobType.String(j.Type), attrSuccess.Bool(false), attrCluster.String(j.Cluster)))
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
 
 		for _, hook := range w.hooksJobDone {
 			hook(ctx, j, err)
@@ -260,6 +791,11 @@ func (w *Worker) WorkOne(ctx context.Context) (didWork bool) {
 			ll.Error("Got an error on setting an error to an errored job", adapter.Err(jErr), adapter.F("job-error", err))
 		}
 
+		event.setOutcome(JobOutcomeErrored, err)
+		if j.deleted {
+			event.setOutcome(JobOutcomeDiscarded, err)
+		}
+
 		return
 	}
 
@@ -267,10 +803,13 @@ func (w *Worker) WorkOne(ctx context.Context) (didWork bool) {
 		hook(ctx, j, nil)
 	}
 
+	event.setOutcome(JobOutcomeSucceeded, nil)
+
 	err = j.Delete(ctx)
 	if err != nil {
 		span.RecordError(fmt.Errorf("failed to delete finished job: %w", err))
 		ll.Error("Got an error on deleting a job", adapter.Err(err))
+		event.setOutcome(JobOutcomeErrored, err)
 	}
 
 	w.mWorked.Add(ctx, 1, metric.WithAttributes(attrJobType.String(j.Type), attrSuccess.Bool(err == nil), attrCluster.String(j.Cluster)))
@@ -278,21 +817,182 @@ func (w *Worker) WorkOne(ctx context.Context) (didWork bool) {
 	return
 }
 
-func (w *Worker) handleUnknownJobType(ctx context.Context, j *Job, span trace.Span, ll adapter.Logger) {
+// startJobSpan starts the span named after the job type. If the job has trace context propagated from the enqueuing
+// code - the span is its child, linked to the worker span, otherwise it is the child of the worker span.
+func (w *Worker) startJobSpan(ctx context.Context, j *Job, workerSpan trace.Span) (context.Context, trace.Span) {
+	opts := []trace.SpanStartOption{
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(
+			attribute.String("job-id", j.ID.String()),
+			attribute.String("job-queue", j.Queue),
+			attribute.String("job-type", j.Type),
+			attribute.Int("job-error-count", int(j.ErrorCount)),
+			attribute.Bool("job-retry", j.ErrorCount > 0),
+		),
+	}
+
+	if len(j.traceContext) > 0 {
+		parentCtx := w.tracePropagator.Extract(ctx, j.traceContext)
+		if trace.SpanContextFromContext(parentCtx).IsRemote() {
+			ctx = parentCtx
+			opts = append(opts, trace.WithLinks(trace.Link{SpanContext: workerSpan.SpanContext()}))
+		}
+	}
+
+	return w.tracer.Start(ctx, j.Type, opts...)
+}
+
+// startHeartbeat starts the job heartbeat in the background if it is enabled for the worker.
+// Returned function stops the heartbeat and waits for it to finish, it is safe to be called several times.
+func (w *Worker) startHeartbeat(ctx context.Context, j *Job, ll adapter.Logger) (stop func()) {
+	if w.heartbeatInterval <= 0 {
+		return func() {}
+	}
+
+	var (
+		once sync.Once
+		wg   sync.WaitGroup
+	)
+
+	done := make(chan struct{})
+	ticker := time.NewTicker(w.heartbeatInterval)
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				stopped, err := j.autoHeartbeat(ctx)
+				if err != nil {
+					ll.Error("Got an error on job heartbeat", adapter.Err(err))
+				}
+				if stopped {
+					ll.Debug("Job transaction is used by the handler, heartbeat is stopped")
+					return
+				}
+			}
+		}
+	}()
+
+	return func() {
+		once.Do(func() {
+			close(done)
+			wg.Wait()
+		})
+	}
+}
+
+func (w *Worker) postponeJob(ctx context.Context, j *Job, err error, errPostpone errJobPostpone, span trace.Span, ll adapter.Logger) {
+	w.mWorked.Add(ctx, 1, metric.WithAttributes(attrJobType.String(j.Type), attrSuccess.Bool(true), attrCluster.String(j.Cluster)))
+
+	for _, hook := range w.hooksJobDone {
+		hook(ctx, j, err)
+	}
+
+	runAt := errPostpone.postponeJobAt(j.now().UTC())
+	if pErr := j.Postpone(ctx, runAt); pErr != nil {
+		span.RecordError(fmt.Errorf("failed to postpone job: %w", pErr))
+		ll.Error("Got an error on postponing a job", adapter.Err(pErr), adapter.F("job-error", err))
+		return
+	}
+
+	ll.Debug("Job postponed", adapter.F("run-at", runAt))
+}
+
+// allowJob checks if the job is allowed to be worked now by the job type limiter. Limiter errors are treated
+// as the job is not allowed.
+func (w *Worker) allowJob(ctx context.Context, limiter Limiter, j *Job, span trace.Span, ll adapter.Logger) bool {
+	allowed, err := limiter.Allow(ctx, j)
+	if err != nil {
+		span.RecordError(fmt.Errorf("failed to check job type limiter: %w", err))
+		ll.Error("Got an error on checking job type limiter", adapter.Err(err))
+		return false
+	}
+
+	return allowed
+}
+
+// postponeLimitedJob puts the job that is not allowed by the job type limiter back to the queue with a short delay,
+// so the worker keeps working jobs of the other types instead of waiting for the limiter.
+func (w *Worker) postponeLimitedJob(ctx context.Context, j *Job, span trace.Span, ll adapter.Logger) {
+	runAt := j.now().UTC().Add(w.limitedJobDelay)
+	if err := j.Postpone(ctx, runAt); err != nil {
+		span.RecordError(fmt.Errorf("failed to postpone rate limited job: %w", err))
+		ll.Error("Got an error on postponing rate limited job", adapter.Err(err))
+		return
+	}
+
+	ll.Debug("Job is rate limited, postponed", adapter.F("run-at", runAt))
+}
+
+// acquireDedupKey registers the job deduplication key as being worked. Key set errors are treated as the key
+// is already registered, so that the same jobs are never worked concurrently.
+func (w *Worker) acquireDedupKey(ctx context.Context, key string, span trace.Span, ll adapter.Logger) bool {
+	acquired, err := w.dedupKeySet.Acquire(ctx, key)
+	if err != nil {
+		span.RecordError(fmt.Errorf("failed to acquire job dedup key: %w", err))
+		ll.Error("Got an error on acquiring job dedup key", adapter.Err(err), adapter.F("dedup-key", key))
+		return false
+	}
+
+	return acquired
+}
+
+func (w *Worker) releaseDedupKey(ctx context.Context, key string, span trace.Span, ll adapter.Logger) {
+	if err := w.dedupKeySet.Release(ctx, key); err != nil {
+		span.RecordError(fmt.Errorf("failed to release job dedup key: %w", err))
+		ll.Error("Got an error on releasing job dedup key", adapter.Err(err), adapter.F("dedup-key", key))
+	}
+}
+
+// postponeDuplicateJob puts the job which dedup key is being worked at the moment back to the queue with
+// a short delay, so the worker keeps working other jobs instead of waiting for the duplicate to finish.
+func (w *Worker) postponeDuplicateJob(ctx context.Context, j *Job, key string, span trace.Span, ll adapter.Logger) {
+	runAt := j.now().UTC().Add(w.dedupDelay)
+	if err := j.Postpone(ctx, runAt); err != nil {
+		span.RecordError(fmt.Errorf("failed to postpone duplicate job: %w", err))
+		ll.Error("Got an error on postponing duplicate job", adapter.Err(err), adapter.F("dedup-key", key))
+		return
+	}
+
+	ll.Debug("Job with the same dedup key is being worked, postponed", adapter.F("dedup-key", key), adapter.F("run-at", runAt))
+}
+
+func (w *Worker) handleUnknownJobType(ctx context.Context, j *Job, span trace.Span, ll adapter.Logger) error {
 	w.mWorked.Add(ctx, 1, metric.WithAttributes(attrJobType.String(j.Type), attrSuccess.Bool(false), attrCluster.String(j.Cluster)))
 
 	span.RecordError(fmt.Errorf("job with unknown type: %q", j.Type))
 	ll.Error("Got a job with unknown type")
 
 	errUnknownType := fmt.Errorf("worker[id=%s] unknown job type: %q", w.id, j.Type)
-	if err := j.Error(ctx, errUnknownType); err != nil {
-		span.RecordError(fmt.Errorf("failed to mark job as error: %w", err))
-		ll.Error("Got an error on setting an error to unknown job", adapter.Err(err))
+	switch w.unknownJobPolicy.action {
+	case unknownJobDiscard:
+		if err := j.discard(ctx, errUnknownType); err != nil {
+			span.RecordError(fmt.Errorf("failed to discard unknown job: %w", err))
+			ll.Error("Got an error on discarding unknown job", adapter.Err(err))
+		}
+	case unknownJobDeadLetter:
+		if err := j.moveToQueue(ctx, w.unknownJobPolicy.queue, errUnknownType); err != nil {
+			span.RecordError(fmt.Errorf("failed to move unknown job to dead letter queue: %w", err))
+			ll.Error("Got an error on moving unknown job to dead letter queue", adapter.Err(err))
+		}
+	default:
+		if err := j.Error(ctx, errUnknownType); err != nil {
+			span.RecordError(fmt.Errorf("failed to mark job as error: %w", err))
+			ll.Error("Got an error on setting an error to unknown job", adapter.Err(err))
+		}
 	}
 
 	for _, hook := range w.hooksUnknownJobType {
 		hook(ctx, j, errUnknownType)
 	}
+
+	return errUnknownType
 }
 
 func (w *Worker) initMetrics() (err error) {
@@ -315,11 +1015,15 @@ func (w *Worker) initMetrics() (err error) {
 	return nil
 }
 
-func (w *Worker) markJobDone(ctx context.Context, j *Job, processingStartedAt time.Time, span trace.Span, ll adapter.Logger) {
+func (w *Worker) markJobDone(ctx context.Context, j *Job, processingStartedAt time.Time, span trace.Span, ll adapter.Logger, event *JobEvent) {
 	if err := j.Done(ctx); err != nil {
 		span.RecordError(fmt.Errorf("failed to mark job as done: %w", err))
 		ll.Error("Failed to mark job as done", adapter.Err(err))
 
+		if event.Outcome == JobOutcomeSucceeded {
+			event.setOutcome(JobOutcomeErrored, err)
+		}
+
 		// let user handle critical job failure
 		for _, hook := range w.hooksJobUndone {
 			hook(ctx, j, err)
@@ -332,11 +1036,20 @@ func (w *Worker) markJobDone(ctx context.Context, j *Job, processingStartedAt ti
 		metric.WithAttributes(attrJobType.String(j.Type)),
 		metric.WithAttributes(attrCluster.String(j.Cluster)),
 	)
+
+	event.Duration = time.Since(processingStartedAt)
+}
+
+// sendJobEvent sends the event to the event sink, jobs that were released without being worked are not reported.
+func (w *Worker) sendJobEvent(event JobEvent) {
+	if w.eventSink != nil && event.Outcome != JobOutcomeReleased {
+		w.eventSink(event)
+	}
 }
 
 // recoverPanic tries to handle panics in job execution.
 // A stacktrace is stored into Job last_error.
-func (w *Worker) recoverPanic(ctx context.Context, j *Job, logger adapter.Logger) {
+func (w *Worker) recoverPanic(ctx context.Context, j *Job, logger adapter.Logger, event *JobEvent) {
 	r := recover()
 	if r == nil {
 		return
@@ -344,6 +1057,10 @@ func (w *Worker) recoverPanic(ctx context.Context, j *Job, logger adapter.Logger
 
 	defer w.recoverPanicRecovery(ctx, j, logger)
 
+	jobSpan := trace.SpanFromContext(ctx)
+	jobSpan.RecordError(ErrJobPanicked)
+	jobSpan.SetStatus(codes.Error, ErrJobPanicked.Error())
+
 	ctx, span := w.tracer.Start(ctx, "Worker.recoverPanic")
 	defer span.End()
 
@@ -354,6 +1071,8 @@ func (w *Worker) recoverPanic(ctx context.Context, j *Job, logger adapter.Logger
 	logger.Error("Job panicked", adapter.F("stacktrace", stacktrace))
 
 	errPanic := fmt.Errorf("%w:\n%s", ErrJobPanicked, stacktrace)
+	event.setOutcome(JobOutcomePanicked, errPanic)
+
 	for _, hook := range w.hooksJobDone {
 		hook(ctx, j, errPanic)
 	}
@@ -410,6 +1129,7 @@ func buildStackTrace(r any, bufSize int, logger adapter.Logger) string {
 type WorkerPool struct {
 	wm           WorkMap
 	interval     time.Duration
+	pollJitter   float64
 	queue        string
 	c            *Client
 	workers      []*Worker
@@ -417,8 +1137,15 @@ type WorkerPool struct {
 	logger       adapter.Logger
 	mu           sync.Mutex
 	running      bool
+	paused       bool
 	pollStrategy PollStrategy
+	locker       JobLocker
 	jobTTL       time.Duration
+	minPriority  JobPriority
+
+	maxIdleInterval      time.Duration
+	maxLockErrorInterval time.Duration
+	stallThreshold       time.Duration
 
 	graceful    bool
 	gracefulCtx func() context.Context
@@ -426,15 +1153,64 @@ type WorkerPool struct {
 	tracer trace.Tracer
 	meter  metric.Meter
 
+	tracePropagator propagation.TextMapPropagator
+
+	wmTx WorkMapTx
+
 	unknownJobTypeWF WorkFunc
+	unknownJobPolicy UnknownJobPolicy
+
+	limiter *rate.Limiter
+
+	retryPolicies map[string]RetryPolicy
+
+	typeLimiters    map[string]Limiter
+	limitedJobDelay time.Duration
+
+	dedupKey    DedupKeyFunc
+	dedupKeySet DedupKeySet
+	dedupDelay  time.Duration
+
+	heartbeatInterval time.Duration
+	batchSize         int
 
 	hooksJobLocked      []HookFunc
 	hooksUnknownJobType []HookFunc
 	hooksJobDone        []HookFunc
 	hooksJobUndone      []HookFunc
 
+	eventSink JobEventSink
+
+	onIdle func()
+	onBusy func()
+	// idleMu guards busyWorkers and serialises the pool idle and busy callbacks
+	idleMu      sync.Mutex
+	busyWorkers int
+
 	panicStackBufSize int
 	spanWorkOneNoJob  bool
+
+	workerOptions []WorkerOption
+	stopped       bool
+
+	queueCounts map[string]int
+	// workerQueues is the queue for every worker index built from the queueCounts
+	workerQueues []string
+
+	draining        bool
+	drainEmptyPolls int
+
+	// grp and grpCtx are set while the pool is running to start the workers added by Resize
+	grp           *errgroup.Group
+	grpCtx        context.Context
+	workerCancels []context.CancelFunc
+	// workerExits are closed when the worker with the given index exits, exiting keeps them for the workers
+	// removed by Resize until they exit, so the worker started with the same index and ID waits for them
+	workerExits   []chan struct{}
+	exiting       map[int]chan struct{}
+	activeWorkers int
+	// drained is closed when all the workers of the draining pool are finished
+	drained chan struct{}
 }
 
 // NewWorkerPool creates a new WorkerPool with count workers using the Client c.
@@ -442,86 +1218,428 @@ type WorkerPool struct {
 // Each Worker in the pool default to a poll interval of 5 seconds, which can be
 // overridden by WithPoolPollInterval option. The default queue is the
 // nameless queue "", which can be overridden by WithPoolQueue option.
+//
+// Every Worker in the pool gets stable ID in the form of "<pool-id>/worker-<idx>", that is available in logs
+// and in the context passed to the WorkFunc and hooks, see GetWorkerID.
+//
+// Pool may spread its workers across several queues with WithPoolQueueCounts option, in this case the number
+// of workers is defined by the option and count is ignored.
 func NewWorkerPool(c *Client, wm WorkMap, poolSize int, options ...WorkerPoolOption) (*WorkerPool, error) {
 	w := WorkerPool{
 		wm:           wm,
 		interval:     defaultPollInterval,
+		pollJitter:   defaultPollJitter,
 		queue:        defaultQueueName,
 		c:            c,
 		id:           RandomStringID(),
-		workers:      make([]*Worker, poolSize),
 		logger:       adapter.NoOpLogger{},
 		pollStrategy: PriorityPollStrategy,
 		tracer:       noopT.NewTracerProvider().Tracer("noop"),
 		meter:        noopM.NewMeterProvider().Meter("noop"),
 
 		panicStackBufSize: defaultPanicStackBufSize,
+		drainEmptyPolls:   defaultDrainEmptyPolls,
+		limitedJobDelay:   defaultLimitedJobDelay,
+		dedupDelay:        defaultDedupDelay,
+		minPriority:       JobPriorityLowest,
 	}
 
 	for _, option := range options {
 		option(&w)
 	}
 
+	if w.dedupKey != nil && w.dedupKeySet == nil {
+		// key set is shared between all the workers to deduplicate jobs for the whole pool
+		w.dedupKeySet = NewDedupKeySet()
+	}
+
+	if w.queueCounts != nil {
+		// queue counts define the pool size, so the poolSize argument is ignored
+		w.workerQueues = queuesLayout(w.queueCounts)
+		poolSize = len(w.workerQueues)
+	}
+
+	w.workers = make([]*Worker, poolSize)
+	w.workerCancels = make([]context.CancelFunc, poolSize)
+	w.workerExits = make([]chan struct{}, poolSize)
+	w.exiting = make(map[int]chan struct{})
+
 	w.logger = w.logger.With(adapter.F("worker-pool-id", w.id))
 
-	var err error
 	for i := range w.workers {
-		w.workers[i], err = NewWorker(
-			w.c,
-			w.wm,
-			WithWorkerPollInterval(w.interval),
-			WithWorkerQueue(w.queue),
-			WithWorkerID(fmt.Sprintf("%s/worker-%d", w.id, i)),
-			WithWorkerLogger(w.logger),
-			WithWorkerPollStrategy(w.pollStrategy),
-			WithWorkerTracer(w.tracer),
-			WithWorkerMeter(w.meter),
-			WithWorkerHooksJobLocked(w.hooksJobLocked...),
-			WithWorkerHooksUnknownJobType(w.hooksUnknownJobType...),
-			WithWorkerHooksJobDone(w.hooksJobDone...),
-			WithWorkerHooksJobUndone(w.hooksJobUndone...),
-			WithWorkerPanicStackBufSize(w.panicStackBufSize),
-			WithWorkerSpanWorkOneNoJob(w.spanWorkOneNoJob),
-			WithWorkerJobTTL(w.jobTTL),
-			WithWorkerUnknownJobWorkFunc(w.unknownJobTypeWF),
-		)
-
+		worker, err := w.newWorker(i)
 		if err != nil {
-			return nil, fmt.Errorf("could not init worker instance: %w", err)
+			return nil, err
 		}
-
-		w.workers[i].graceful = w.graceful
-		w.workers[i].gracefulCtx = w.gracefulCtx
+		w.workers[i] = worker
 	}
 
 	return &w, nil
 }
 
+// queuesLayout returns the queue for every worker index according to the queue counts, queues are sorted by name.
+func queuesLayout(queueCounts map[string]int) []string {
+	queues := make([]string, 0, len(queueCounts))
+	for queue := range queueCounts {
+		queues = append(queues, queue)
+	}
+	sort.Strings(queues)
+
+	var layout []string
+	for _, queue := range queues {
+		for i := 0; i < queueCounts[queue]; i++ {
+			layout = append(layout, queue)
+		}
+	}
+
+	return layout
+}
+
+// newWorker creates a new Worker with the given index using the current pool options.
+func (w *WorkerPool) newWorker(idx int) (*Worker, error) {
+	queue := w.queue
+	if idx < len(w.workerQueues) {
+		queue = w.workerQueues[idx]
+	}
+
+	workerOptions := []WorkerOption{
+		WithWorkerPollInterval(w.interval),
+		WithWorkerPollJitter(w.pollJitter),
+		WithWorkerQueue(queue),
+		WithWorkerLogger(w.logger),
+		WithWorkerPollStrategy(w.pollStrategy),
+		WithWorkerTracer(w.tracer),
+		WithWorkerTracePropagator(w.tracePropagator),
+		WithWorkerMeter(w.meter),
+		WithWorkerHooksJobLocked(w.hooksJobLocked...),
+		WithWorkerHooksUnknownJobType(w.hooksUnknownJobType...),
+		WithWorkerHooksJobDone(w.hooksJobDone...),
+		WithWorkerHooksJobUndone(w.hooksJobUndone...),
+		WithWorkerEventSink(w.eventSink),
+		WithWorkerPanicStackBufSize(w.panicStackBufSize),
+		WithWorkerSpanWorkOneNoJob(w.spanWorkOneNoJob),
+		WithWorkerJobTTL(w.jobTTL),
+		WithWorkerUnknownJobPolicy(w.unknownJobPolicy),
+		WithWorkerWorkMapTx(w.wmTx),
+		WithWorkerUnknownJobWorkFunc(w.unknownJobTypeWF),
+		WithWorkerHeartbeat(w.heartbeatInterval),
+		WithWorkerBatchSize(w.batchSize),
+		WithWorkerMinPriority(w.minPriority),
+		WithWorkerJobLocker(w.locker),
+		WithWorkerIdleBackoff(w.maxIdleInterval),
+		WithWorkerLoopBackoff(w.maxLockErrorInterval),
+		WithWorkerStallThreshold(w.stallThreshold),
+		WithWorkerDrainEmptyPolls(w.drainEmptyPolls),
+		WithWorkerLimitedJobDelay(w.limitedJobDelay),
+		WithWorkerDedupKey(w.dedupKey),
+		WithWorkerDedupKeySet(w.dedupKeySet),
+		WithWorkerDedupDelay(w.dedupDelay),
+		WithWorkerTypeRetryPolicy(w.retryPolicies),
+	}
+	for jobType, limiter := range w.typeLimiters {
+		// limiters are shared between all the workers to enforce the rate for the whole pool
+		workerOptions = append(workerOptions, WithWorkerTypeLimiter(jobType, limiter))
+	}
+	workerOptions = append(workerOptions, w.workerOptions...)
+	// worker ID is always set by the pool to keep it stable and unique within the pool
+	workerOptions = append(workerOptions, WithWorkerID(fmt.Sprintf("%s/worker-%d", w.id, idx)))
+
+	worker, err := NewWorker(w.c, w.wm, workerOptions...)
+	if err != nil {
+		return nil, fmt.Errorf("could not init worker instance: %w", err)
+	}
+
+	if w.graceful {
+		worker.graceful = w.graceful
+		worker.gracefulCtx = w.gracefulCtx
+	}
+	if w.limiter != nil {
+		// limiter is shared between all the workers to enforce the rate for the whole pool
+		worker.limiter = w.limiter
+	}
+	worker.staggerStart = true
+	worker.idleChanged = w.workerIdleChanged
+	if w.paused {
+		worker.Pause()
+	}
+	if w.draining {
+		worker.Drain()
+	}
+
+	return worker, nil
+}
+
+// workerIdleChanged tracks the number of busy pool workers and calls the pool idle callback when all the workers
+// become idle and the busy callback when the first of them becomes busy.
+func (w *WorkerPool) workerIdleChanged(idle bool) {
+	w.idleMu.Lock()
+	defer w.idleMu.Unlock()
+
+	if idle {
+		w.busyWorkers--
+		if w.busyWorkers == 0 && w.onIdle != nil {
+			w.onIdle()
+		}
+		return
+	}
+
+	w.busyWorkers++
+	if w.busyWorkers == 1 && w.onBusy != nil {
+		w.onBusy()
+	}
+}
+
 // Run runs all the Workers in the WorkerPool in own goroutines.
 // Run blocks until all workers exit. Use context cancellation for
-// shutdown.
+// shutdown. Pool can not be run again once it was shut down, ErrWorkerPoolStopped is returned in this case.
 func (w *WorkerPool) Run(ctx context.Context) error {
+	w.mu.Lock()
+	stopped := w.stopped
+	w.mu.Unlock()
+
+	if stopped {
+		return fmt.Errorf("worker pool[id=%s]: %w", w.id, ErrWorkerPoolStopped)
+	}
+
 	return RunLock(ctx, w.runGroup, &w.mu, &w.running, w.id)
 }
 
+// Pause makes all the Workers in the running WorkerPool stop locking new jobs after they finish the current ones,
+// until the pool is resumed. See Worker.Pause for details.
+func (w *WorkerPool) Pause() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.paused = true
+	for _, worker := range w.workers {
+		worker.Pause()
+	}
+}
+
+// Resume makes all the Workers in the paused WorkerPool continue locking and working jobs.
+// See Worker.Resume for details.
+func (w *WorkerPool) Resume() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.paused = false
+	for _, worker := range w.workers {
+		worker.Resume()
+	}
+}
+
+// Drain makes all the Workers in the WorkerPool work the queue until it is empty, and then Run returns on its own
+// once all the workers are finished. See Worker.Drain for details.
+func (w *WorkerPool) Drain() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.draining = true
+	for _, worker := range w.workers {
+		worker.Drain()
+	}
+
+	w.checkDrained()
+}
+
+// checkDrained signals the running pool to finish when it is draining and all the workers are finished.
+// Must be called with the pool mutex locked.
+func (w *WorkerPool) checkDrained() {
+	if w.draining && w.activeWorkers == 0 && w.drained != nil {
+		close(w.drained)
+		w.drained = nil
+	}
+}
+
+// Status returns current WorkerPool status.
+func (w *WorkerPool) Status() WorkerStatus {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return workerStatus(w.running, w.paused)
+}
+
+// Running returns true if the WorkerPool is running and is not paused, so it is working jobs.
+func (w *WorkerPool) Running() bool {
+	return w.Status() == WorkerStatusRunning
+}
+
 // WorkOne tries to consume single message from the queue.
 func (w *WorkerPool) WorkOne(ctx context.Context) (didWork bool) {
-	return w.workers[0].WorkOne(ctx)
+	w.mu.Lock()
+	if len(w.workers) == 0 {
+		w.mu.Unlock()
+		return false
+	}
+	worker := w.workers[0]
+	w.mu.Unlock()
+
+	return worker.WorkOne(ctx)
+}
+
+// WorkOneResult tries to consume single message from the queue the same way WorkOne does and returns WorkResult,
+// see Worker.WorkOneResult for details.
+func (w *WorkerPool) WorkOneResult(ctx context.Context) (WorkResult, error) {
+	w.mu.Lock()
+	if len(w.workers) == 0 {
+		w.mu.Unlock()
+		return WorkResult{Outcome: JobOutcomeNoJob}, nil
+	}
+	worker := w.workers[0]
+	w.mu.Unlock()
+
+	return worker.WorkOneResult(ctx)
+}
+
+// Step performs a single iteration of every Worker loop in the pool sequentially, in the order of the workers
+// indexes, within the caller goroutine. Returns the number of workers that worked a Job.
+// Step must not be used together with Run for the same pool instance.
+func (w *WorkerPool) Step(ctx context.Context) (worked int) {
+	w.mu.Lock()
+	workers := w.workers
+	w.mu.Unlock()
+
+	for i, worker := range workers {
+		if worker.Step(setWorkerIdx(ctx, i)) {
+			worked++
+		}
+	}
+
+	return worked
+}
+
+// Size returns the current number of Workers in the WorkerPool.
+func (w *WorkerPool) Size() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return len(w.workers)
+}
+
+// Resize changes the number of Workers in the WorkerPool to n. It is safe to call Resize on the running pool
+// while jobs are being worked.
+//
+// Growing the pool creates new Workers with the current pool options and starts them right away if the pool
+// is running. Shrinking the pool stops the surplus Workers with the highest indexes the same way the whole pool
+// is stopped on context cancellation, so they finish their in-flight jobs (depending on the graceful shutdown
+// settings) and exit. Resize does not wait for the surplus Workers to exit, but the Worker started later with
+// the same index waits for the stopped one to exit first, so there are never two running Workers with the same ID.
+//
+// Once the running pool is shutting down, Resize does not start new Workers anymore.
+func (w *WorkerPool) Resize(n int) error {
+	if n < 0 {
+		return fmt.Errorf("worker pool[id=%s]: invalid size %d", w.id, n)
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for i := len(w.workers); i < n; i++ {
+		worker, err := w.newWorker(i)
+		if err != nil {
+			return err
+		}
+
+		w.workers = append(w.workers, worker)
+		w.workerCancels = append(w.workerCancels, nil)
+		w.workerExits = append(w.workerExits, nil)
+		if w.grp != nil {
+			w.startWorker(i)
+		}
+	}
+
+	for i := n; i < len(w.workers); i++ {
+		if cancel := w.workerCancels[i]; cancel != nil {
+			cancel()
+		}
+		if exit := w.workerExits[i]; exit != nil {
+			w.exiting[i] = exit
+		}
+	}
+	if n < len(w.workers) {
+		w.workers = w.workers[:n:n]
+		w.workerCancels = w.workerCancels[:n:n]
+		w.workerExits = w.workerExits[:n:n]
+	}
+
+	w.logger.Info("Worker pool resized", adapter.F("size", n))
+
+	return nil
 }
 
 // runGroup starts all the Workers in the WorkerPool in own goroutines
 // managed by errgroup.Group.
 func (w *WorkerPool) runGroup(ctx context.Context) error {
 	defer w.logger.Info("Worker pool finished")
+	defer func() {
+		w.mu.Lock()
+		w.stopped = true
+		w.mu.Unlock()
+	}()
 
 	grp, ctx := errgroup.WithContext(ctx)
+
+	drained := make(chan struct{})
+
+	w.mu.Lock()
+	w.grp, w.grpCtx, w.drained = grp, ctx, drained
 	for i := range w.workers {
-		idx := i
-		worker := w.workers[idx]
-		grp.Go(func() error {
-			return worker.Run(setWorkerIdx(ctx, idx))
-		})
+		w.startWorker(i)
 	}
+	w.checkDrained()
+	w.mu.Unlock()
+
+	// the pool may be resized while running, so workers are started and stopped until the pool is shut down
+	// or drained, and only then the group could be waited
+	select {
+	case <-ctx.Done():
+	case <-drained:
+	}
+
+	w.mu.Lock()
+	w.grp, w.grpCtx, w.drained = nil, nil, nil
+	w.mu.Unlock()
 
 	return grp.Wait()
 }
+
+// startWorker starts the Worker with the given index in the running pool group.
+// Must be called with the pool mutex locked.
+func (w *WorkerPool) startWorker(idx int) {
+	workerCtx, cancel := context.WithCancel(w.grpCtx)
+	w.workerCancels[idx] = cancel
+
+	// worker exits only after the previous worker with the same index, so waiting for the latest one is enough
+	prevExit := w.exiting[idx]
+	delete(w.exiting, idx)
+	exit := make(chan struct{})
+	w.workerExits[idx] = exit
+
+	w.activeWorkers++
+
+	worker := w.workers[idx]
+	w.grp.Go(func() error {
+		defer func() {
+			cancel()
+			close(exit)
+
+			w.mu.Lock()
+			if w.exiting[idx] == exit {
+				delete(w.exiting, idx)
+			}
+			w.activeWorkers--
+			w.checkDrained()
+			w.mu.Unlock()
+		}()
+
+		if prevExit != nil {
+			// worker removed by Resize may still be finishing its job
+			<-prevExit
+			if workerCtx.Err() != nil {
+				return nil
+			}
+		}
+
+		return worker.Run(setWorkerIdx(workerCtx, idx))
+	})
+}
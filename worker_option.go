@@ -5,7 +5,9 @@ import (
 	"time"
 
 	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/propagation"
 	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/time/rate"
 
 	"github.com/vortex14/gue/v7/adapter"
 )
@@ -24,6 +26,56 @@ func WithWorkerPollInterval(d time.Duration) WorkerOption {
 	}
 }
 
+// WithWorkerPollJitter sets the max random jitter added to the poll interval as a fraction of the interval,
+// so the workers do not poll the DB in sync. Default value is 0.1, values are limited to the range of [0, 0.5],
+// so the worker never sleeps longer than 1.5× poll interval. Zero value disables jitter.
+func WithWorkerPollJitter(frac float64) WorkerOption {
+	return func(w *Worker) {
+		w.pollJitter = clampPollJitter(frac)
+	}
+}
+
+// WithWorkerIdleBackoff enables adaptive poll interval while the queue is empty: every poll that finds no jobs doubles
+// the interval up to maxInterval, and the interval is reset to the one set by WithWorkerPollInterval as soon as
+// the job is found. Poll jitter is applied to the current interval. Idle backoff is disabled by default and when
+// maxInterval is not greater than the poll interval.
+func WithWorkerIdleBackoff(maxInterval time.Duration) WorkerOption {
+	return func(w *Worker) {
+		w.maxIdleInterval = maxInterval
+	}
+}
+
+// WithWorkerLoopBackoff enables exponential backoff on consecutive failures to lock a job, e.g. while the DB
+// is unavailable: every failed attempt doubles the poll interval up to maxInterval, so the worker does not flood
+// the DB and the logs with the failing queries. The interval is reset as soon as the lock attempt succeeds,
+// regardless of whether the job was found or not. The backoff wait is interrupted by the worker context
+// cancellation. Loop backoff is disabled by default and when maxInterval is not greater than the poll interval.
+func WithWorkerLoopBackoff(maxInterval time.Duration) WorkerOption {
+	return func(w *Worker) {
+		w.maxLockErrorInterval = maxInterval
+	}
+}
+
+// WithWorkerStallThreshold makes Worker.Health report the worker as not healthy when a single job is being worked
+// longer than d or when the attempts to lock a job keep failing longer than d, e.g. while the DB is unavailable.
+// Stall threshold is disabled by default, so only the stopped worker is reported as not healthy.
+func WithWorkerStallThreshold(d time.Duration) WorkerOption {
+	return func(w *Worker) {
+		w.stallThreshold = d
+	}
+}
+
+func clampPollJitter(frac float64) float64 {
+	if frac < 0 {
+		return 0
+	}
+	if frac > maxPollJitter {
+		return maxPollJitter
+	}
+
+	return frac
+}
+
 // WithWorkerQueue overrides default worker queue name with the given value.
 func WithWorkerQueue(queue string) WorkerOption {
 	return func(w *Worker) {
@@ -52,6 +104,16 @@ func WithWorkerTracer(tracer trace.Tracer) WorkerOption {
 	}
 }
 
+// WithWorkerTracePropagator enables extraction of the trace context propagated from the enqueuing code, see
+// WithClientTracePropagator. Every job is worked within the span named after the job type, that is the child
+// of the enqueuing span if the job has trace context propagated, with the job attributes and handler error
+// or panic recorded. Disabled by default.
+func WithWorkerTracePropagator(propagator propagation.TextMapPropagator) WorkerOption {
+	return func(w *Worker) {
+		w.tracePropagator = propagator
+	}
+}
+
 // WithWorkerMeter sets metric.Meter instance to the worker.
 func WithWorkerMeter(meter metric.Meter) WorkerOption {
 	return func(w *Worker) {
@@ -91,13 +153,62 @@ func WithWorkerHooksUnknownJobType(hooks ...HookFunc) WorkerOption {
 // WithWorkerHooksJobDone sets hooks that are called when worker finished working the job,
 // right before the successfully executed job will be removed or errored job handler will be called to decide
 // if the Job will be re-queued or discarded.
-// Error field is set for the cases when the job was worked with an error.
+// Error field is set for the cases when the job was worked with an error, including the job being postponed
+// with ErrPostponeJobIn or ErrPostponeJobAt.
 func WithWorkerHooksJobDone(hooks ...HookFunc) WorkerOption {
 	return func(w *Worker) {
 		w.hooksJobDone = hooks
 	}
 }
 
+// WithWorkerJobLocker sets the custom JobLocker the worker locks jobs with instead of the Client, e.g. the fake one
+// to test the worker and handlers without the DB. Custom locker takes precedence over the poll strategy,
+// min priority and batch size, as it locks jobs one by one with JobLocker.LockJob.
+func WithWorkerJobLocker(locker JobLocker) WorkerOption {
+	return func(w *Worker) {
+		w.locker = locker
+	}
+}
+
+// WithWorkerMinPriority sets the lowest priority of the jobs the worker locks, so the jobs with the lower priority
+// are ignored entirely. As a lower number means a higher priority, the worker locks only the jobs with
+// Job.Priority <= minPriority, e.g. WithWorkerMinPriority(JobPriorityHigh) allows to run the dedicated worker
+// for the high priority jobs alongside the catch-all one. Jobs are still locked in the order defined by the
+// worker poll strategy. Default is JobPriorityLowest, that means jobs of all priorities are locked.
+func WithWorkerMinPriority(minPriority JobPriority) WorkerOption {
+	return func(w *Worker) {
+		w.minPriority = minPriority
+	}
+}
+
+// WithWorkerOnIdle sets the callback that is called when the worker transitions from working jobs to finding none,
+// i.e. the first time it finds no job after it worked one. Busy worker that is stopped becomes idle as well.
+// Callback is called synchronously by the worker, so it must not block.
+func WithWorkerOnIdle(onIdle func()) WorkerOption {
+	return func(w *Worker) {
+		w.onIdle = onIdle
+	}
+}
+
+// WithWorkerOnBusy sets the callback that is called when the worker transitions from finding no jobs to working them,
+// including the first job worked after the worker started. Callback is called synchronously by the worker, so it must
+// not block.
+func WithWorkerOnBusy(onBusy func()) WorkerOption {
+	return func(w *Worker) {
+		w.onBusy = onBusy
+	}
+}
+
+// WithWorkerEventSink sets the sink that receives JobEvent with the outcome of every job worked by the worker.
+// Events are not sent for the jobs that were released back to the queue without being worked. In batch mode
+// (see WithWorkerBatchSize) events are sent once the whole batch is worked and its transaction is committed.
+// Use NewJobEventChannel to receive events over the channel, e.g. to wait for the specific job to be worked in tests.
+func WithWorkerEventSink(sink JobEventSink) WorkerOption {
+	return func(w *Worker) {
+		w.eventSink = sink
+	}
+}
+
 // WithWorkerHooksJobUndone sets hooks that are called when worker fails to mark the job as done.
 // This is an exceptional situation, most likely caused by transaction failed to be committed.
 // Hook implementation MUST NOT rely on the transaction provided by the job as it may already be marked as failed.
@@ -147,15 +258,149 @@ func WithWorkerJobTTL(d time.Duration) WorkerOption {
 	}
 }
 
-// WithWorkerUnknownJobWorkFunc sets the handler for unknown job types.
-// When the handler is set - hooks set with WithWorkerHooksUnknownJobType are never called as the job is
-// handled in the regular way.
+// WithWorkerUnknownJobWorkFunc sets the handler for unknown job types, that is called instead of the default
+// behaviour of marking the job as errored and instead of the policy set with WithWorkerUnknownJobPolicy.
+// When the handler is set - hooks set with WithWorkerHooksUnknownJobType are never called as the job is handled
+// in the regular way, so the handler result defines what happens to the job:
+//
+//   - return nil to delete the job;
+//   - return error built with ErrPostponeJobIn to skip the job without increasing its error count, so it can be
+//     picked up by the worker that knows the job type, e.g. during rolling deploy;
+//   - enqueue the job copy to the dead-letter queue with Job.EnqueueInTx and return nil to move the job there
+//     atomically;
+//   - return any other error to mark the job as errored, the same as the default behaviour does.
 func WithWorkerUnknownJobWorkFunc(wf WorkFunc) WorkerOption {
 	return func(w *Worker) {
 		w.unknownJobTypeWF = wf
 	}
 }
 
+// WithWorkerWorkMapTx sets the handlers that perform the jobs within the job transaction, so the handler database
+// changes are committed atomically with the job completion, see WorkFuncTx for details. Job types registered
+// in the WorkMap take precedence over the ones registered in WorkMapTx. Heartbeat set with WithWorkerHeartbeat
+// is not run for these jobs, as the handler uses the job transaction.
+func WithWorkerWorkMapTx(wm WorkMapTx) WorkerOption {
+	return func(w *Worker) {
+		w.wmTx = wm
+	}
+}
+
+// WithWorkerUnknownJobPolicy sets what happens to the jobs which types are not registered in the WorkMap,
+// e.g. after the deploy that removed the handler. Default policy is UnknownJobRetry. Hooks set with
+// WithWorkerHooksUnknownJobType are called for all the policies. Policy is not applied when the handler is set
+// with WithWorkerUnknownJobWorkFunc, as unknown jobs are worked with that handler instead.
+func WithWorkerUnknownJobPolicy(policy UnknownJobPolicy) WorkerOption {
+	return func(w *Worker) {
+		w.unknownJobPolicy = policy
+	}
+}
+
+// WithWorkerRateLimit limits the rate of jobs being worked by the worker using token bucket algorithm, where rps is
+// the number of jobs allowed per second and burst is the bucket size. When there is no token available - worker
+// blocks with the locked job until it gets one or until the worker context is cancelled, in the latter case the job
+// is released back to the queue without being worked. Non-positive rps disables rate limiting, burst values less
+// than 1 are considered as 1.
+func WithWorkerRateLimit(rps float64, burst int) WorkerOption {
+	return func(w *Worker) {
+		w.limiter = newRateLimiter(rps, burst)
+	}
+}
+
+// WithWorkerTypeRateLimit limits the rate of jobs of the given type being worked by the worker, see NewRateLimiter
+// for the parameters details. Unlike WithWorkerRateLimit, worker does not block when there is no token available,
+// but postpones the job for a short delay (see WithWorkerLimitedJobDelay) and continues working jobs of other types.
+func WithWorkerTypeRateLimit(jobType string, rps float64, burst int) WorkerOption {
+	return WithWorkerTypeLimiter(jobType, NewRateLimiter(rps, burst))
+}
+
+// WithWorkerTypeLimiter sets custom Limiter for the jobs of the given type. Jobs that are not allowed by the limiter
+// are postponed for a short delay (see WithWorkerLimitedJobDelay) without being worked or marked as failed.
+func WithWorkerTypeLimiter(jobType string, limiter Limiter) WorkerOption {
+	return func(w *Worker) {
+		if w.typeLimiters == nil {
+			w.typeLimiters = make(map[string]Limiter)
+		}
+		w.typeLimiters[jobType] = limiter
+	}
+}
+
+// WithWorkerLimitedJobDelay overrides default delay of 1 second the job that is not allowed by the job type limiter
+// is postponed for.
+func WithWorkerLimitedJobDelay(d time.Duration) WorkerOption {
+	return func(w *Worker) {
+		w.limitedJobDelay = d
+	}
+}
+
+// WithWorkerDedupKey sets the function that computes the deduplication key for every job, so the jobs with the same
+// key are never worked concurrently, even if several of them are enqueued. Key is registered in the DedupKeySet
+// for the duration of the job handler and released even if the handler panics. Job which key is being worked
+// at the moment is postponed for a short delay (see WithWorkerDedupDelay) without being worked or marked
+// as failed. Worker uses in-memory key set unless it is set with WithWorkerDedupKeySet.
+func WithWorkerDedupKey(f DedupKeyFunc) WorkerOption {
+	return func(w *Worker) {
+		w.dedupKey = f
+	}
+}
+
+// WithWorkerDedupDelay overrides default delay of 5 seconds the job which dedup key is being worked at the moment
+// is postponed for.
+func WithWorkerDedupDelay(d time.Duration) WorkerOption {
+	return func(w *Worker) {
+		w.dedupDelay = d
+	}
+}
+
+// WithWorkerDedupKeySet sets custom DedupKeySet for the keys set with WithWorkerDedupKey, e.g. to deduplicate
+// jobs across several application instances.
+func WithWorkerDedupKeySet(set DedupKeySet) WorkerOption {
+	return func(w *Worker) {
+		w.dedupKeySet = set
+	}
+}
+
+// WithWorkerTypeRetryPolicy sets retry policies per job type, that are used instead of the client backoff
+// to reschedule errored jobs of the given types. Policies can be parsed from the configuration with ParseRetryPolicy.
+// Jobs rescheduled with ErrRescheduleJobIn or ErrRescheduleJobAt errors are not affected by the policies.
+func WithWorkerTypeRetryPolicy(policies map[string]RetryPolicy) WorkerOption {
+	return func(w *Worker) {
+		w.retryPolicies = policies
+	}
+}
+
+// WithWorkerHeartbeat enables periodic job heartbeat with the given interval while the job handler is running,
+// see Job.Heartbeat for details. Heartbeat is stopped as soon as the handler returns or panics.
+// Heartbeat queries are executed within the job transaction, so heartbeat is stopped once the handler takes
+// the transaction with Job.Tx(). Such handlers should call Job.Heartbeat between own queries instead, if needed.
+// Non-positive interval disables heartbeat.
+func WithWorkerHeartbeat(interval time.Duration) WorkerOption {
+	return func(w *Worker) {
+		w.heartbeatInterval = interval
+	}
+}
+
+// WithWorkerBatchSize enables batch mode for the worker, when it locks up to n jobs at once using
+// Client.LockJobs and works them one by one before polling the queue again. Every job in the batch is handled
+// in isolation - it gets its own hooks calls, error handling and panic recovery, and is worked within its own
+// savepoint of the shared transaction, so the job that aborts the transaction is rolled back and released back
+// to the queue as is, while the changes of the other jobs are committed. Once the worker context is cancelled,
+// the jobs of the batch that are not worked yet are released back to the queue without being worked.
+// Values less than 2 disable batch mode. Poll strategy is ignored in batch mode.
+func WithWorkerBatchSize(n int) WorkerOption {
+	return func(w *Worker) {
+		w.batchSize = n
+	}
+}
+
+// WithWorkerDrainEmptyPolls overrides default number of consecutive polls with no jobs found, after which
+// the draining worker considers the queue empty and exits, see Worker.Drain. Default value is 1,
+// values less than 1 are treated as 1.
+func WithWorkerDrainEmptyPolls(n int) WorkerOption {
+	return func(w *Worker) {
+		w.drainEmptyPolls = n
+	}
+}
+
 // WithPoolPollInterval overrides default poll interval with the given value.
 // Poll interval is the "sleep" duration if there were no jobs found in the DB.
 func WithPoolPollInterval(d time.Duration) WorkerPoolOption {
@@ -164,6 +409,46 @@ func WithPoolPollInterval(d time.Duration) WorkerPoolOption {
 	}
 }
 
+// WithPoolQueueCounts spreads the pool workers across several queues with the given number of workers per queue.
+// The sum of the counts defines the pool size and overrides the count passed to NewWorkerPool. Workers get
+// indexes in the order of the queue names, e.g. {"b": 1, "a": 2} makes workers 0 and 1 work queue "a" and
+// worker 2 work queue "b". Workers added with WorkerPool.Resize above the sum of the counts work the pool queue
+// set with WithPoolQueue.
+func WithPoolQueueCounts(queueCounts map[string]int) WorkerPoolOption {
+	return func(w *WorkerPool) {
+		w.queueCounts = queueCounts
+	}
+}
+
+// WithPoolPollJitter sets the max random poll interval jitter for every worker in the pool, see WithWorkerPollJitter.
+// Pool workers also start with the random delay within the same jitter, so they do not query the DB all at once.
+func WithPoolPollJitter(frac float64) WorkerPoolOption {
+	return func(w *WorkerPool) {
+		w.pollJitter = clampPollJitter(frac)
+	}
+}
+
+// WithPoolIdleBackoff calls WithWorkerIdleBackoff for every worker in the pool.
+func WithPoolIdleBackoff(maxInterval time.Duration) WorkerPoolOption {
+	return func(w *WorkerPool) {
+		w.maxIdleInterval = maxInterval
+	}
+}
+
+// WithPoolLoopBackoff calls WithWorkerLoopBackoff for every worker in the pool.
+func WithPoolLoopBackoff(maxInterval time.Duration) WorkerPoolOption {
+	return func(w *WorkerPool) {
+		w.maxLockErrorInterval = maxInterval
+	}
+}
+
+// WithPoolStallThreshold calls WithWorkerStallThreshold for every worker in the pool.
+func WithPoolStallThreshold(d time.Duration) WorkerPoolOption {
+	return func(w *WorkerPool) {
+		w.stallThreshold = d
+	}
+}
+
 // WithPoolQueue overrides default worker queue name with the given value.
 func WithPoolQueue(queue string) WorkerPoolOption {
 	return func(w *WorkerPool) {
@@ -199,6 +484,14 @@ func WithPoolTracer(tracer trace.Tracer) WorkerPoolOption {
 	}
 }
 
+// WithPoolTracePropagator enables extraction of the propagated trace context for every worker in the pool.
+// See WithWorkerTracePropagator for details.
+func WithPoolTracePropagator(propagator propagation.TextMapPropagator) WorkerPoolOption {
+	return func(w *WorkerPool) {
+		w.tracePropagator = propagator
+	}
+}
+
 // WithPoolMeter sets metric.Meter instance to every worker in the pool.
 func WithPoolMeter(meter metric.Meter) WorkerPoolOption {
 	return func(w *WorkerPool) {
@@ -229,6 +522,46 @@ func WithPoolHooksJobDone(hooks ...HookFunc) WorkerPoolOption {
 	}
 }
 
+// WithPoolJobLocker calls WithWorkerJobLocker for every worker in the pool.
+func WithPoolJobLocker(locker JobLocker) WorkerPoolOption {
+	return func(w *WorkerPool) {
+		w.locker = locker
+	}
+}
+
+// WithPoolMinPriority calls WithWorkerMinPriority for every worker in the pool.
+func WithPoolMinPriority(minPriority JobPriority) WorkerPoolOption {
+	return func(w *WorkerPool) {
+		w.minPriority = minPriority
+	}
+}
+
+// WithPoolOnIdle sets the callback that is called when all the workers in the pool become idle at the same time,
+// see WithWorkerOnIdle for details. Unlike most of the pool options, the callback is not set for every worker,
+// but tracks the state of the whole pool. Callback may be called from any of the pool workers goroutines,
+// but never concurrently with the other pool idle or busy callback.
+func WithPoolOnIdle(onIdle func()) WorkerPoolOption {
+	return func(w *WorkerPool) {
+		w.onIdle = onIdle
+	}
+}
+
+// WithPoolOnBusy sets the callback that is called when the first worker in the idle pool becomes busy,
+// see WithPoolOnIdle for details.
+func WithPoolOnBusy(onBusy func()) WorkerPoolOption {
+	return func(w *WorkerPool) {
+		w.onBusy = onBusy
+	}
+}
+
+// WithPoolEventSink calls WithWorkerEventSink for every worker in the pool, so the sink receives events from all
+// the pool workers. Use JobEvent.WorkerID to distinguish the workers.
+func WithPoolEventSink(sink JobEventSink) WorkerPoolOption {
+	return func(w *WorkerPool) {
+		w.eventSink = sink
+	}
+}
+
 // WithPoolHooksJobUndone calls WithWorkerHooksJobUndone for every worker in the pool.
 func WithPoolHooksJobUndone(hooks ...HookFunc) WorkerPoolOption {
 	return func(w *WorkerPool) {
@@ -279,3 +612,127 @@ func WithPoolUnknownJobWorkFunc(wf WorkFunc) WorkerPoolOption {
 		w.unknownJobTypeWF = wf
 	}
 }
+
+// WithPoolWorkMapTx calls WithWorkerWorkMapTx for every worker in the pool.
+func WithPoolWorkMapTx(wm WorkMapTx) WorkerPoolOption {
+	return func(w *WorkerPool) {
+		w.wmTx = wm
+	}
+}
+
+// WithPoolUnknownJobPolicy calls WithWorkerUnknownJobPolicy for every worker in the pool.
+func WithPoolUnknownJobPolicy(policy UnknownJobPolicy) WorkerPoolOption {
+	return func(w *WorkerPool) {
+		w.unknownJobPolicy = policy
+	}
+}
+
+// WithPoolRateLimit limits the rate of jobs being worked by the worker pool. The limiter is shared between all the
+// workers in the pool, so the rate is enforced for the pool as a whole and not per worker.
+// See WithWorkerRateLimit for details.
+func WithPoolRateLimit(rps float64, burst int) WorkerPoolOption {
+	return func(w *WorkerPool) {
+		w.limiter = newRateLimiter(rps, burst)
+	}
+}
+
+// WithPoolTypeRateLimit limits the rate of jobs of the given type being worked by the worker pool. The limiter is
+// shared between all the workers in the pool. See WithWorkerTypeRateLimit for details.
+func WithPoolTypeRateLimit(jobType string, rps float64, burst int) WorkerPoolOption {
+	return WithPoolTypeLimiter(jobType, NewRateLimiter(rps, burst))
+}
+
+// WithPoolTypeLimiter sets custom Limiter for the jobs of the given type for all the workers in the pool.
+// See WithWorkerTypeLimiter for details.
+func WithPoolTypeLimiter(jobType string, limiter Limiter) WorkerPoolOption {
+	return func(w *WorkerPool) {
+		if w.typeLimiters == nil {
+			w.typeLimiters = make(map[string]Limiter)
+		}
+		w.typeLimiters[jobType] = limiter
+	}
+}
+
+// WithPoolLimitedJobDelay overrides default delay for the rate limited jobs for every worker in the pool.
+// See WithWorkerLimitedJobDelay for details.
+func WithPoolLimitedJobDelay(d time.Duration) WorkerPoolOption {
+	return func(w *WorkerPool) {
+		w.limitedJobDelay = d
+	}
+}
+
+// WithPoolDedupKey calls WithWorkerDedupKey for every worker in the pool. Workers share the same DedupKeySet,
+// so the jobs with the same key are not worked concurrently by the pool.
+func WithPoolDedupKey(f DedupKeyFunc) WorkerPoolOption {
+	return func(w *WorkerPool) {
+		w.dedupKey = f
+	}
+}
+
+// WithPoolDedupDelay overrides default delay for the duplicate jobs for every worker in the pool.
+// See WithWorkerDedupDelay for details.
+func WithPoolDedupDelay(d time.Duration) WorkerPoolOption {
+	return func(w *WorkerPool) {
+		w.dedupDelay = d
+	}
+}
+
+// WithPoolDedupKeySet sets custom DedupKeySet shared by all the workers in the pool.
+// See WithWorkerDedupKeySet for details.
+func WithPoolDedupKeySet(set DedupKeySet) WorkerPoolOption {
+	return func(w *WorkerPool) {
+		w.dedupKeySet = set
+	}
+}
+
+// WithPoolTypeRetryPolicy sets retry policies per job type for every worker in the pool.
+// See WithWorkerTypeRetryPolicy for details.
+func WithPoolTypeRetryPolicy(policies map[string]RetryPolicy) WorkerPoolOption {
+	return func(w *WorkerPool) {
+		w.retryPolicies = policies
+	}
+}
+
+func newRateLimiter(rps float64, burst int) *rate.Limiter {
+	if rps <= 0 {
+		return nil
+	}
+
+	if burst < 1 {
+		burst = 1
+	}
+
+	return rate.NewLimiter(rate.Limit(rps), burst)
+}
+
+// WithPoolWorkerOptions sets options that are applied to every worker in the pool on top of the ones derived from
+// the pool options, so they may be used to set any worker property that has no pool-level option or override
+// the pool-level one for workers. Worker ID is always set by the pool, so WithWorkerID has no effect here.
+func WithPoolWorkerOptions(options ...WorkerOption) WorkerPoolOption {
+	return func(w *WorkerPool) {
+		w.workerOptions = options
+	}
+}
+
+// WithPoolHeartbeat enables periodic job heartbeat for every worker in the pool.
+// See WithWorkerHeartbeat for details.
+func WithPoolHeartbeat(interval time.Duration) WorkerPoolOption {
+	return func(w *WorkerPool) {
+		w.heartbeatInterval = interval
+	}
+}
+
+// WithPoolBatchSize enables batch mode for every worker in the pool. See WithWorkerBatchSize for details.
+func WithPoolBatchSize(n int) WorkerPoolOption {
+	return func(w *WorkerPool) {
+		w.batchSize = n
+	}
+}
+
+// WithPoolDrainEmptyPolls overrides default number of consecutive polls with no jobs found for every worker
+// in the pool. See WithWorkerDrainEmptyPolls for details.
+func WithPoolDrainEmptyPolls(n int) WorkerPoolOption {
+	return func(w *WorkerPool) {
+		w.drainEmptyPolls = n
+	}
+}
@@ -2,6 +2,7 @@ package gue
 
 import (
 	"context"
+	"fmt"
 	"testing"
 	"time"
 
@@ -532,3 +533,438 @@ func TestWithPoolUnknownJobWorkFunc(t *testing.T) {
 	}
 	assert.Equal(t, 2, wfCalled)
 }
+
+func TestWithWorkerRateLimit(t *testing.T) {
+	workerWOutRateLimit, err := NewWorker(nil, dummyWM)
+	require.NoError(t, err)
+	assert.Nil(t, workerWOutRateLimit.limiter)
+
+	workerWithDisabledRateLimit, err := NewWorker(nil, dummyWM, WithWorkerRateLimit(0, 10))
+	require.NoError(t, err)
+	assert.Nil(t, workerWithDisabledRateLimit.limiter)
+
+	workerWithRateLimit, err := NewWorker(nil, dummyWM, WithWorkerRateLimit(100, 5))
+	require.NoError(t, err)
+	require.NotNil(t, workerWithRateLimit.limiter)
+	assert.Equal(t, 100.0, float64(workerWithRateLimit.limiter.Limit()))
+	assert.Equal(t, 5, workerWithRateLimit.limiter.Burst())
+
+	workerWithZeroBurst, err := NewWorker(nil, dummyWM, WithWorkerRateLimit(100, 0))
+	require.NoError(t, err)
+	require.NotNil(t, workerWithZeroBurst.limiter)
+	assert.Equal(t, 1, workerWithZeroBurst.limiter.Burst())
+}
+
+func TestWithPoolRateLimit(t *testing.T) {
+	poolWOutRateLimit, err := NewWorkerPool(nil, dummyWM, 2)
+	require.NoError(t, err)
+	assert.Nil(t, poolWOutRateLimit.limiter)
+	for _, w := range poolWOutRateLimit.workers {
+		assert.Nil(t, w.limiter)
+	}
+
+	poolWithRateLimit, err := NewWorkerPool(nil, dummyWM, 3, WithPoolRateLimit(100, 5))
+	require.NoError(t, err)
+	require.NotNil(t, poolWithRateLimit.limiter)
+	for _, w := range poolWithRateLimit.workers {
+		// all the workers must share the same limiter instance
+		assert.Same(t, poolWithRateLimit.limiter, w.limiter)
+	}
+}
+
+func TestWithPoolWorkerOptions(t *testing.T) {
+	poolWOutWorkerOptions, err := NewWorkerPool(nil, dummyWM, 2, WithPoolID("pool"), WithPoolJobTTL(time.Minute))
+	require.NoError(t, err)
+	for i, w := range poolWOutWorkerOptions.workers {
+		assert.Equal(t, fmt.Sprintf("pool/worker-%d", i), w.id)
+		assert.Equal(t, time.Minute, w.jobTTL)
+		assert.Nil(t, w.limiter)
+	}
+
+	poolWithWorkerOptions, err := NewWorkerPool(
+		nil,
+		dummyWM,
+		3,
+		WithPoolID("pool"),
+		WithPoolJobTTL(time.Minute),
+		WithPoolWorkerOptions(
+			WithWorkerID("ignored"),
+			WithWorkerJobTTL(time.Hour),
+			WithWorkerRateLimit(10, 1),
+			WithWorkerGracefulShutdown(nil),
+		),
+	)
+	require.NoError(t, err)
+	for i, w := range poolWithWorkerOptions.workers {
+		assert.Equal(t, fmt.Sprintf("pool/worker-%d", i), w.id)
+		assert.Equal(t, time.Hour, w.jobTTL)
+		assert.True(t, w.graceful)
+		require.NotNil(t, w.limiter)
+
+		// per-worker option means per-worker limiter
+		for j, ww := range poolWithWorkerOptions.workers {
+			if i != j {
+				assert.NotSame(t, w.limiter, ww.limiter)
+			}
+		}
+	}
+}
+
+func TestWithWorkerHeartbeat(t *testing.T) {
+	workerWOutHeartbeat, err := NewWorker(nil, dummyWM)
+	require.NoError(t, err)
+	assert.Equal(t, time.Duration(0), workerWOutHeartbeat.heartbeatInterval)
+
+	workerWithHeartbeat, err := NewWorker(nil, dummyWM, WithWorkerHeartbeat(time.Second))
+	require.NoError(t, err)
+	assert.Equal(t, time.Second, workerWithHeartbeat.heartbeatInterval)
+}
+
+func TestWithPoolHeartbeat(t *testing.T) {
+	poolWOutHeartbeat, err := NewWorkerPool(nil, dummyWM, 2)
+	require.NoError(t, err)
+	for _, w := range poolWOutHeartbeat.workers {
+		assert.Equal(t, time.Duration(0), w.heartbeatInterval)
+	}
+
+	poolWithHeartbeat, err := NewWorkerPool(nil, dummyWM, 2, WithPoolHeartbeat(time.Second))
+	require.NoError(t, err)
+	for _, w := range poolWithHeartbeat.workers {
+		assert.Equal(t, time.Second, w.heartbeatInterval)
+	}
+}
+
+func TestWithWorkerBatchSize(t *testing.T) {
+	workerWOutBatch, err := NewWorker(nil, dummyWM)
+	require.NoError(t, err)
+	assert.Equal(t, 0, workerWOutBatch.batchSize)
+
+	workerWithBatch, err := NewWorker(nil, dummyWM, WithWorkerBatchSize(10))
+	require.NoError(t, err)
+	assert.Equal(t, 10, workerWithBatch.batchSize)
+}
+
+func TestWithPoolBatchSize(t *testing.T) {
+	poolWithBatch, err := NewWorkerPool(nil, dummyWM, 2, WithPoolBatchSize(10))
+	require.NoError(t, err)
+	for _, w := range poolWithBatch.workers {
+		assert.Equal(t, 10, w.batchSize)
+	}
+}
+
+type dummyLocker struct {
+	queues []string
+}
+
+func (l *dummyLocker) LockJob(_ context.Context, queue string) (*Job, error) {
+	l.queues = append(l.queues, queue)
+	return nil, nil
+}
+
+func TestWithWorkerJobLocker(t *testing.T) {
+	ctx := context.Background()
+	locker := new(dummyLocker)
+
+	w, err := NewWorker(nil, dummyWM, WithWorkerQueue("locker"), WithWorkerBatchSize(10), WithWorkerJobLocker(locker))
+	require.NoError(t, err)
+
+	// custom locker is used even with the batch size set
+	assert.False(t, w.Step(ctx))
+	assert.False(t, w.WorkOne(ctx))
+	assert.Equal(t, []string{"locker", "locker"}, locker.queues)
+}
+
+func TestWithPoolJobLocker(t *testing.T) {
+	locker := new(dummyLocker)
+
+	pool, err := NewWorkerPool(nil, dummyWM, 2, WithPoolJobLocker(locker))
+	require.NoError(t, err)
+	for _, w := range pool.workers {
+		assert.Same(t, locker, w.locker)
+	}
+}
+
+func TestWithWorkerMinPriority(t *testing.T) {
+	workerWOutMinPriority, err := NewWorker(nil, dummyWM)
+	require.NoError(t, err)
+	assert.Equal(t, JobPriorityLowest, workerWOutMinPriority.minPriority)
+
+	workerWithMinPriority, err := NewWorker(nil, dummyWM, WithWorkerMinPriority(JobPriorityHigh))
+	require.NoError(t, err)
+	assert.Equal(t, JobPriorityHigh, workerWithMinPriority.minPriority)
+}
+
+func TestWithPoolMinPriority(t *testing.T) {
+	poolWOutMinPriority, err := NewWorkerPool(nil, dummyWM, 2)
+	require.NoError(t, err)
+	for _, w := range poolWOutMinPriority.workers {
+		assert.Equal(t, JobPriorityLowest, w.minPriority)
+	}
+
+	poolWithMinPriority, err := NewWorkerPool(nil, dummyWM, 2, WithPoolMinPriority(JobPriorityHigh))
+	require.NoError(t, err)
+	for _, w := range poolWithMinPriority.workers {
+		assert.Equal(t, JobPriorityHigh, w.minPriority)
+	}
+}
+
+func TestWithPoolIdleBackoff(t *testing.T) {
+	poolWithIdleBackoff, err := NewWorkerPool(nil, dummyWM, 2, WithPoolIdleBackoff(time.Minute))
+	require.NoError(t, err)
+	for _, w := range poolWithIdleBackoff.workers {
+		assert.Equal(t, time.Minute, w.maxIdleInterval)
+	}
+}
+
+func TestWithPoolLoopBackoff(t *testing.T) {
+	poolWithLoopBackoff, err := NewWorkerPool(nil, dummyWM, 2, WithPoolLoopBackoff(time.Minute))
+	require.NoError(t, err)
+	for _, w := range poolWithLoopBackoff.workers {
+		assert.Equal(t, time.Minute, w.maxLockErrorInterval)
+	}
+}
+
+func TestWithWorkerDedupKey(t *testing.T) {
+	workerWOutDedupKey, err := NewWorker(nil, dummyWM)
+	require.NoError(t, err)
+	assert.Nil(t, workerWOutDedupKey.dedupKey)
+	assert.Nil(t, workerWOutDedupKey.dedupKeySet)
+	assert.Equal(t, defaultDedupDelay, workerWOutDedupKey.dedupDelay)
+
+	workerWithDedupKey, err := NewWorker(nil, dummyWM, WithWorkerDedupKey(func(j *Job) string { return j.Type }))
+	require.NoError(t, err)
+	assert.NotNil(t, workerWithDedupKey.dedupKey)
+	assert.NotNil(t, workerWithDedupKey.dedupKeySet)
+
+	set := NewDedupKeySet()
+	workerWithDedupKeySet, err := NewWorker(
+		nil, dummyWM,
+		WithWorkerDedupKey(func(j *Job) string { return j.Type }),
+		WithWorkerDedupKeySet(set),
+		WithWorkerDedupDelay(time.Minute),
+	)
+	require.NoError(t, err)
+	assert.Same(t, set, workerWithDedupKeySet.dedupKeySet)
+	assert.Equal(t, time.Minute, workerWithDedupKeySet.dedupDelay)
+}
+
+func TestWithPoolDedupKey(t *testing.T) {
+	poolWithDedupKey, err := NewWorkerPool(nil, dummyWM, 2, WithPoolDedupKey(func(j *Job) string { return j.Type }))
+	require.NoError(t, err)
+	require.NotNil(t, poolWithDedupKey.dedupKeySet)
+	for _, w := range poolWithDedupKey.workers {
+		assert.NotNil(t, w.dedupKey)
+		// key set is shared by all the workers
+		assert.Same(t, poolWithDedupKey.dedupKeySet, w.dedupKeySet)
+	}
+
+	set := NewDedupKeySet()
+	poolWithDedupKeySet, err := NewWorkerPool(
+		nil, dummyWM, 2,
+		WithPoolDedupKey(func(j *Job) string { return j.Type }),
+		WithPoolDedupKeySet(set),
+		WithPoolDedupDelay(time.Minute),
+	)
+	require.NoError(t, err)
+	for _, w := range poolWithDedupKeySet.workers {
+		assert.Same(t, set, w.dedupKeySet)
+		assert.Equal(t, time.Minute, w.dedupDelay)
+	}
+}
+
+func TestWithWorkerStallThreshold(t *testing.T) {
+	workerWOutStallThreshold, err := NewWorker(nil, dummyWM)
+	require.NoError(t, err)
+	assert.Zero(t, workerWOutStallThreshold.stallThreshold)
+
+	workerWithStallThreshold, err := NewWorker(nil, dummyWM, WithWorkerStallThreshold(time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, time.Minute, workerWithStallThreshold.stallThreshold)
+}
+
+func TestWithPoolStallThreshold(t *testing.T) {
+	poolWithStallThreshold, err := NewWorkerPool(nil, dummyWM, 2, WithPoolStallThreshold(time.Minute))
+	require.NoError(t, err)
+	for _, w := range poolWithStallThreshold.workers {
+		assert.Equal(t, time.Minute, w.stallThreshold)
+	}
+}
+
+func TestWithWorkerWorkMapTx(t *testing.T) {
+	wmTx := WorkMapTx{
+		"MyJobTx": func(ctx context.Context, tx adapter.Tx, j *Job) error { return nil },
+	}
+
+	workerWOutWorkMapTx, err := NewWorker(nil, dummyWM)
+	require.NoError(t, err)
+	assert.Nil(t, workerWOutWorkMapTx.wmTx)
+
+	workerWithWorkMapTx, err := NewWorker(nil, dummyWM, WithWorkerWorkMapTx(wmTx))
+	require.NoError(t, err)
+	assert.Len(t, workerWithWorkMapTx.wmTx, 1)
+	assert.Contains(t, workerWithWorkMapTx.wmTx, "MyJobTx")
+}
+
+func TestWithPoolWorkMapTx(t *testing.T) {
+	wmTx := WorkMapTx{
+		"MyJobTx": func(ctx context.Context, tx adapter.Tx, j *Job) error { return nil },
+	}
+
+	poolWithWorkMapTx, err := NewWorkerPool(nil, dummyWM, 2, WithPoolWorkMapTx(wmTx))
+	require.NoError(t, err)
+	for _, w := range poolWithWorkMapTx.workers {
+		assert.Contains(t, w.wmTx, "MyJobTx")
+	}
+}
+
+func TestWithWorkerDrainEmptyPolls(t *testing.T) {
+	workerWOutDrainEmptyPolls, err := NewWorker(nil, dummyWM)
+	require.NoError(t, err)
+	assert.Equal(t, defaultDrainEmptyPolls, workerWOutDrainEmptyPolls.drainEmptyPolls)
+
+	workerWithDrainEmptyPolls, err := NewWorker(nil, dummyWM, WithWorkerDrainEmptyPolls(3))
+	require.NoError(t, err)
+	assert.Equal(t, 3, workerWithDrainEmptyPolls.drainEmptyPolls)
+}
+
+func TestWithPoolDrainEmptyPolls(t *testing.T) {
+	poolWithDrainEmptyPolls, err := NewWorkerPool(nil, dummyWM, 2, WithPoolDrainEmptyPolls(3))
+	require.NoError(t, err)
+	for _, w := range poolWithDrainEmptyPolls.workers {
+		assert.Equal(t, 3, w.drainEmptyPolls)
+	}
+}
+
+func TestWithWorkerTypeLimiter(t *testing.T) {
+	workerWOutLimiters, err := NewWorker(nil, dummyWM)
+	require.NoError(t, err)
+	assert.Empty(t, workerWOutLimiters.typeLimiters)
+	assert.Equal(t, defaultLimitedJobDelay, workerWOutLimiters.limitedJobDelay)
+
+	limiter := NewRateLimiter(1, 1)
+	workerWithLimiters, err := NewWorker(
+		nil, dummyWM,
+		WithWorkerTypeLimiter("foo", limiter),
+		WithWorkerTypeRateLimit("bar", 10, 5),
+		WithWorkerLimitedJobDelay(5*time.Second),
+	)
+	require.NoError(t, err)
+	require.Len(t, workerWithLimiters.typeLimiters, 2)
+	assert.Same(t, limiter, workerWithLimiters.typeLimiters["foo"])
+	assert.NotNil(t, workerWithLimiters.typeLimiters["bar"])
+	assert.Equal(t, 5*time.Second, workerWithLimiters.limitedJobDelay)
+}
+
+func TestWithPoolTypeLimiter(t *testing.T) {
+	limiter := NewRateLimiter(1, 1)
+	poolWithLimiters, err := NewWorkerPool(
+		nil, dummyWM, 2,
+		WithPoolTypeLimiter("foo", limiter),
+		WithPoolTypeRateLimit("bar", 10, 5),
+		WithPoolLimitedJobDelay(5*time.Second),
+	)
+	require.NoError(t, err)
+
+	// limiters are shared between the workers
+	for _, w := range poolWithLimiters.workers {
+		require.Len(t, w.typeLimiters, 2)
+		assert.Same(t, limiter, w.typeLimiters["foo"])
+		assert.Same(t, poolWithLimiters.workers[0].typeLimiters["bar"], w.typeLimiters["bar"])
+		assert.Equal(t, 5*time.Second, w.limitedJobDelay)
+	}
+}
+
+func TestWithWorkerTypeRetryPolicy(t *testing.T) {
+	workerWOutPolicies, err := NewWorker(nil, dummyWM)
+	require.NoError(t, err)
+	assert.Empty(t, workerWOutPolicies.retryPolicies)
+
+	policies := map[string]RetryPolicy{"MyJob": {Backoff: BackoffNever}}
+	workerWithPolicies, err := NewWorker(nil, dummyWM, WithWorkerTypeRetryPolicy(policies))
+	require.NoError(t, err)
+	assert.Len(t, workerWithPolicies.retryPolicies, 1)
+}
+
+func TestWithPoolTypeRetryPolicy(t *testing.T) {
+	policies := map[string]RetryPolicy{"MyJob": {Backoff: BackoffNever}}
+	poolWithPolicies, err := NewWorkerPool(nil, dummyWM, 2, WithPoolTypeRetryPolicy(policies))
+	require.NoError(t, err)
+	for _, w := range poolWithPolicies.workers {
+		assert.Len(t, w.retryPolicies, 1)
+	}
+}
+
+func TestWithWorkerPollJitter(t *testing.T) {
+	workerWOutJitter, err := NewWorker(nil, dummyWM)
+	require.NoError(t, err)
+	assert.Equal(t, defaultPollJitter, workerWOutJitter.pollJitter)
+	assert.False(t, workerWOutJitter.staggerStart)
+
+	for frac, expected := range map[float64]float64{
+		0:    0,
+		0.25: 0.25,
+		-1:   0,
+		2:    maxPollJitter,
+	} {
+		workerWithJitter, err := NewWorker(nil, dummyWM, WithWorkerPollJitter(frac))
+		require.NoError(t, err)
+		assert.Equal(t, expected, workerWithJitter.pollJitter)
+	}
+}
+
+func TestWithPoolPollJitter(t *testing.T) {
+	poolWOutJitter, err := NewWorkerPool(nil, dummyWM, 2)
+	require.NoError(t, err)
+	for _, w := range poolWOutJitter.workers {
+		assert.Equal(t, defaultPollJitter, w.pollJitter)
+		assert.True(t, w.staggerStart)
+	}
+
+	poolWithJitter, err := NewWorkerPool(nil, dummyWM, 2, WithPoolPollJitter(5))
+	require.NoError(t, err)
+	for _, w := range poolWithJitter.workers {
+		assert.Equal(t, maxPollJitter, w.pollJitter)
+	}
+}
+
+func TestWithPoolQueueCounts(t *testing.T) {
+	poolWithQueueCounts, err := NewWorkerPool(
+		nil, dummyWM, 10,
+		WithPoolID("pool"),
+		WithPoolQueue("default"),
+		WithPoolQueueCounts(map[string]int{"b": 1, "a": 2, "c": 0, "d": -1}),
+	)
+	require.NoError(t, err)
+
+	// queue counts override pool size
+	require.Equal(t, 3, poolWithQueueCounts.Size())
+	for i, queue := range []string{"a", "a", "b"} {
+		assert.Equal(t, queue, poolWithQueueCounts.workers[i].queue)
+		assert.Equal(t, fmt.Sprintf("pool/worker-%d", i), poolWithQueueCounts.workers[i].id)
+	}
+
+	// workers above the queue counts work the pool queue
+	require.NoError(t, poolWithQueueCounts.Resize(4))
+	assert.Equal(t, "default", poolWithQueueCounts.workers[3].queue)
+
+	require.NoError(t, poolWithQueueCounts.Resize(1))
+	require.NoError(t, poolWithQueueCounts.Resize(3))
+	assert.Equal(t, "b", poolWithQueueCounts.workers[2].queue)
+}
+
+func TestWithPoolEventSink(t *testing.T) {
+	var events []JobEvent
+	sink := func(e JobEvent) { events = append(events, e) }
+
+	pool, err := NewWorkerPool(nil, dummyWM, 3, WithPoolEventSink(sink))
+	require.NoError(t, err)
+	for _, w := range pool.workers {
+		require.NotNil(t, w.eventSink)
+		w.eventSink(JobEvent{WorkerID: w.id})
+	}
+
+	require.Len(t, events, 3)
+	for i, w := range pool.workers {
+		assert.Equal(t, w.id, events[i].WorkerID)
+	}
+}
@@ -0,0 +1,83 @@
+package gue
+
+import "time"
+
+// Preset pool sizes and settings, see NewLatencyPool, NewThroughputPool and NewBackgroundPool.
+const (
+	latencyPoolSize        = 10
+	latencyPollInterval    = 100 * time.Millisecond
+	latencyPollJitter      = 0.2
+	latencyJobTTL          = 30 * time.Second
+	throughputPoolSize     = 4
+	throughputPollInterval = time.Second
+	throughputBatchSize    = 10
+	throughputJobTTL       = 5 * time.Minute
+	backgroundPoolSize     = 2
+	backgroundPollInterval = 30 * time.Second
+	backgroundJobTTL       = time.Hour
+	backgroundHeartbeat    = time.Minute
+)
+
+// WorkerPoolConfig is the effective configuration of the WorkerPool, see WorkerPool.Config.
+type WorkerPoolConfig struct {
+	Size              int
+	Queue             string
+	PollInterval      time.Duration
+	PollJitter        float64
+	PollStrategy      PollStrategy
+	JobTTL            time.Duration
+	BatchSize         int
+	HeartbeatInterval time.Duration
+	GracefulShutdown  bool
+}
+
+// Config returns the effective WorkerPool configuration, e.g. to check the settings chosen by the presets.
+func (w *WorkerPool) Config() WorkerPoolConfig {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return WorkerPoolConfig{
+		Size:              len(w.workers),
+		Queue:             w.queue,
+		PollInterval:      w.interval,
+		PollJitter:        w.pollJitter,
+		PollStrategy:      w.pollStrategy,
+		JobTTL:            w.jobTTL,
+		BatchSize:         w.batchSize,
+		HeartbeatInterval: w.heartbeatInterval,
+		GracefulShutdown:  w.graceful,
+	}
+}
+
+// NewLatencyPool creates a new WorkerPool tuned for the low job pickup latency: 10 workers polling every 100ms
+// with 20% jitter and 30 seconds job TTL. Options are applied on top of the preset ones, so any preset setting
+// can be overridden, pool size can be changed with WorkerPool.Resize.
+func NewLatencyPool(c *Client, wm WorkMap, options ...WorkerPoolOption) (*WorkerPool, error) {
+	return NewWorkerPool(c, wm, latencyPoolSize, append([]WorkerPoolOption{
+		WithPoolPollInterval(latencyPollInterval),
+		WithPoolPollJitter(latencyPollJitter),
+		WithPoolJobTTL(latencyJobTTL),
+	}, options...)...)
+}
+
+// NewThroughputPool creates a new WorkerPool tuned for the high jobs throughput: 4 workers locking jobs
+// in batches of 10, polling every second with 5 minutes job TTL. Options are applied on top of the preset ones,
+// so any preset setting can be overridden, pool size can be changed with WorkerPool.Resize.
+func NewThroughputPool(c *Client, wm WorkMap, options ...WorkerPoolOption) (*WorkerPool, error) {
+	return NewWorkerPool(c, wm, throughputPoolSize, append([]WorkerPoolOption{
+		WithPoolPollInterval(throughputPollInterval),
+		WithPoolBatchSize(throughputBatchSize),
+		WithPoolJobTTL(throughputJobTTL),
+	}, options...)...)
+}
+
+// NewBackgroundPool creates a new WorkerPool tuned for the long-running background jobs: 2 workers polling every
+// 30 seconds with 1 hour job TTL and job heartbeat every minute. Options are applied on top of the preset ones,
+// so any preset setting can be overridden, pool size can be changed with WorkerPool.Resize.
+func NewBackgroundPool(c *Client, wm WorkMap, options ...WorkerPoolOption) (*WorkerPool, error) {
+	return NewWorkerPool(c, wm, backgroundPoolSize, append([]WorkerPoolOption{
+		WithPoolPollInterval(backgroundPollInterval),
+		WithPoolJobTTL(backgroundJobTTL),
+		WithPoolHeartbeat(backgroundHeartbeat),
+	}, options...)...)
+}
@@ -0,0 +1,99 @@
+package gue
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWorkerPool_Config(t *testing.T) {
+	pool, err := NewWorkerPool(nil, dummyWM, 3)
+	require.NoError(t, err)
+
+	assert.Equal(t, WorkerPoolConfig{
+		Size:         3,
+		Queue:        defaultQueueName,
+		PollInterval: defaultPollInterval,
+		PollJitter:   defaultPollJitter,
+		PollStrategy: PriorityPollStrategy,
+	}, pool.Config())
+
+	require.NoError(t, pool.Resize(5))
+	assert.Equal(t, 5, pool.Config().Size)
+}
+
+func TestPoolPresets(t *testing.T) {
+	for name, tc := range map[string]struct {
+		newPool  func(c *Client, wm WorkMap, options ...WorkerPoolOption) (*WorkerPool, error)
+		expected WorkerPoolConfig
+	}{
+		"latency": {
+			newPool: NewLatencyPool,
+			expected: WorkerPoolConfig{
+				Size:         10,
+				PollInterval: 100 * time.Millisecond,
+				PollJitter:   0.2,
+				PollStrategy: PriorityPollStrategy,
+				JobTTL:       30 * time.Second,
+			},
+		},
+		"throughput": {
+			newPool: NewThroughputPool,
+			expected: WorkerPoolConfig{
+				Size:         4,
+				PollInterval: time.Second,
+				PollJitter:   defaultPollJitter,
+				PollStrategy: PriorityPollStrategy,
+				JobTTL:       5 * time.Minute,
+				BatchSize:    10,
+			},
+		},
+		"background": {
+			newPool: NewBackgroundPool,
+			expected: WorkerPoolConfig{
+				Size:              2,
+				PollInterval:      30 * time.Second,
+				PollJitter:        defaultPollJitter,
+				PollStrategy:      PriorityPollStrategy,
+				JobTTL:            time.Hour,
+				HeartbeatInterval: time.Minute,
+			},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			pool, err := tc.newPool(nil, dummyWM)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, pool.Config())
+
+			for _, w := range pool.workers {
+				assert.Equal(t, tc.expected.PollInterval, w.interval)
+				assert.Equal(t, tc.expected.JobTTL, w.jobTTL)
+				assert.Equal(t, tc.expected.BatchSize, w.batchSize)
+				assert.Equal(t, tc.expected.HeartbeatInterval, w.heartbeatInterval)
+			}
+
+			// explicit options override preset values
+			poolOverridden, err := tc.newPool(
+				nil, dummyWM,
+				WithPoolQueue("custom"),
+				WithPoolPollInterval(time.Minute),
+				WithPoolJobTTL(0),
+			)
+			require.NoError(t, err)
+
+			expected := tc.expected
+			expected.Queue = "custom"
+			expected.PollInterval = time.Minute
+			expected.JobTTL = 0
+			assert.Equal(t, expected, poolOverridden.Config())
+
+			for _, w := range poolOverridden.workers {
+				assert.Equal(t, "custom", w.queue)
+				assert.Equal(t, time.Minute, w.interval)
+				assert.Equal(t, time.Duration(0), w.jobTTL)
+			}
+		})
+	}
+}
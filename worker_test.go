@@ -3,12 +3,17 @@ package gue
 import (
 	"context"
 	"errors"
+	"fmt"
+	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/oklog/ulid/v2"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
@@ -147,11 +152,12 @@ func testWorkerPoolRun(t *testing.T, connPool adapter.ConnPool) {
 			defer m.Unlock()
 
 			assert.NotEqual(t, WorkerIdxUnknown, GetWorkerIdx(ctx))
+			assert.Equal(t, fmt.Sprintf("pool/worker-%d", GetWorkerIdx(ctx)), GetWorkerID(ctx))
 
 			jobsWorked++
 			return nil
 		},
-	}, 2)
+	}, 2, WithPoolID("pool"))
 	require.NoError(t, err)
 
 	ctx, cancel := context.WithCancel(context.Background())
@@ -188,6 +194,9 @@ func testWorkerPoolRun(t *testing.T, connPool adapter.ConnPool) {
 	}
 
 	assert.Equal(t, jobsToWork, jobsWorked)
+
+	// pool that was shut down can not be run again
+	assert.ErrorIs(t, w.Run(context.Background()), ErrWorkerPoolStopped)
 }
 
 func TestWorkerPool_WorkOne(t *testing.T) {
@@ -754,3 +763,2085 @@ func TestNewWorkerPool_GracefulShutdown(t *testing.T) {
 	assert.Equal(t, 0, jobCancelled)
 	assert.Equal(t, numWorkers, jobFinished)
 }
+
+func TestWorkerPool_RateLimit(t *testing.T) {
+	for name, openFunc := range adapterTesting.AllAdaptersOpenTestPool {
+		t.Run(name, func(t *testing.T) {
+			testWorkerPoolRateLimit(t, openFunc(t))
+		})
+	}
+}
+
+func testWorkerPoolRateLimit(t *testing.T, connPool adapter.ConnPool) {
+	c, err := NewClient(connPool)
+	require.NoError(t, err)
+
+	var (
+		m          sync.Mutex
+		jobsWorked int
+	)
+
+	w, err := NewWorkerPool(c, WorkMap{
+		"dummy-job": func(ctx context.Context, j *Job) error {
+			m.Lock()
+			defer m.Unlock()
+
+			jobsWorked++
+			return nil
+		},
+	}, 4, WithPoolRateLimit(0.001, 2), WithPoolPollInterval(time.Millisecond))
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	for i := 0; i < 10; i++ {
+		err := c.Enqueue(ctx, &Job{Type: "dummy-job"})
+		require.NoError(t, err)
+	}
+
+	var grp errgroup.Group
+	grp.Go(func() error {
+		return w.Run(ctx)
+	})
+
+	// limiter is refilled once in 1000 seconds, so the pool has only the burst of 2 tokens during the test and every
+	// worker waiting for the shared limiter holds the reservation that makes the tokens number go below zero
+	require.Eventually(t, func() bool {
+		return w.limiter.Tokens() < -3.5
+	}, 5*time.Second, time.Millisecond, "all 4 workers must be waiting for the shared limiter")
+
+	cancel()
+	require.NoError(t, grp.Wait())
+
+	m.Lock()
+	assert.Equal(t, 2, jobsWorked)
+	m.Unlock()
+}
+
+func TestWorker_RateLimitGracefulShutdown(t *testing.T) {
+	c, err := NewClient(nil)
+	require.NoError(t, err)
+
+	// the second job is released back to the queue without being worked or marked as errored
+	releasedTx := new(adapterTesting.Tx)
+	releasedTx.Mock.On("Commit", mock.Anything).Return(nil).Once()
+
+	var polls atomic.Int64
+	poll := idlePollFunc(t, c, true)
+	waiting := make(chan struct{})
+	pollFunc := func(ctx context.Context, queue string) (*Job, error) {
+		switch polls.Add(1) {
+		case 1:
+			return poll(ctx, queue)
+		case 2:
+			close(waiting)
+			return &Job{Type: "MyJob", tx: releasedTx, client: c, backoff: c.backoff, logger: c.logger, now: c.now}, nil
+		default:
+			return nil, nil
+		}
+	}
+
+	var worked atomic.Int64
+	w, err := NewWorker(
+		c, WorkMap{"MyJob": func(ctx context.Context, j *Job) error {
+			worked.Add(1)
+			return nil
+		}},
+		WithWorkerPollInterval(time.Millisecond),
+		WithWorkerRateLimit(0.001, 1),
+		WithWorkerGracefulShutdown(nil),
+		withWorkerPollFunc(pollFunc),
+	)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- w.Run(ctx)
+	}()
+
+	<-waiting
+	// let the worker block in the rate limiter wait with the second job
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("worker is still waiting for the rate limiter after it was stopped")
+	}
+
+	assert.Equal(t, int64(1), worked.Load())
+	releasedTx.Mock.AssertExpectations(t)
+}
+
+func TestHandler(t *testing.T) {
+	ctx := context.Background()
+
+	var called []nestedJobArgs
+	wf := Handler(func(ctx context.Context, j *Job, args nestedJobArgs) error {
+		called = append(called, args)
+		return nil
+	})
+
+	j, err := NewJob("MyJob", nestedJobArgs{Name: "foo", Child: &nestedJobArgs{Name: "bar"}})
+	require.NoError(t, err)
+
+	err = wf(ctx, j)
+	require.NoError(t, err)
+	require.Len(t, called, 1)
+	assert.Equal(t, "foo", called[0].Name)
+	assert.Equal(t, "bar", called[0].Child.Name)
+
+	err = wf(ctx, &Job{Type: "MyJob", Args: []byte(`{invalid]json>`)})
+	require.Error(t, err)
+	assert.Len(t, called, 1)
+
+	// malformed args must never be retried
+	var errReschedule ErrJobReschedule
+	require.ErrorAs(t, err, &errReschedule)
+	assert.True(t, errReschedule.rescheduleJobAt(time.Now()).IsZero())
+
+	handlerErr := errors.New("handler error")
+	wfErr := Handler(func(ctx context.Context, j *Job, args map[string]any) error {
+		return handlerErr
+	})
+	err = wfErr(ctx, &Job{Type: "MyJob", Args: []byte(`{}`)})
+	assert.Same(t, handlerErr, err)
+}
+
+func TestWorker_Heartbeat(t *testing.T) {
+	for name, openFunc := range adapterTesting.AllAdaptersOpenTestPool {
+		t.Run(name, func(t *testing.T) {
+			testWorkerHeartbeat(t, openFunc(t))
+		})
+	}
+}
+
+func testWorkerHeartbeat(t *testing.T, connPool adapter.ConnPool) {
+	ctx := context.Background()
+
+	c, err := NewClient(connPool)
+	require.NoError(t, err)
+
+	wm := WorkMap{
+		"slow-job": func(ctx context.Context, j *Job) error {
+			time.Sleep(300 * time.Millisecond)
+			return nil
+		},
+		"panicking-slow-job": func(ctx context.Context, j *Job) error {
+			time.Sleep(300 * time.Millisecond)
+			panic("the panic msg")
+		},
+	}
+
+	var updatedAt []time.Time
+	jobDoneHook := func(ctx context.Context, j *Job, err error) {
+		// heartbeat is already stopped at this point, so it is safe to use the job transaction
+		var ts time.Time
+		txErr := j.Tx().QueryRow(ctx, `SELECT updated_at FROM gue_jobs WHERE job_id = $1`, j.ID.String()).Scan(&ts)
+		require.NoError(t, txErr)
+		assert.True(t, ts.After(j.CreatedAt), "heartbeat must update job updated_at")
+		updatedAt = append(updatedAt, ts)
+	}
+
+	w, err := NewWorker(c, wm, WithWorkerHeartbeat(50*time.Millisecond), WithWorkerHooksJobDone(jobDoneHook))
+	require.NoError(t, err)
+
+	err = c.Enqueue(ctx, &Job{Type: "slow-job"})
+	require.NoError(t, err)
+	require.True(t, w.WorkOne(ctx))
+
+	panickingJob := Job{Type: "panicking-slow-job"}
+	err = c.Enqueue(ctx, &panickingJob)
+	require.NoError(t, err)
+	require.True(t, w.WorkOne(ctx))
+
+	assert.Len(t, updatedAt, 2)
+
+	// panicked job is errored properly, that means heartbeat did not interfere with the panic recovery
+	j, err := c.LockJobByID(ctx, panickingJob.ID)
+	require.NoError(t, err)
+	require.NotNil(t, j)
+
+	t.Cleanup(func() {
+		err := j.Done(ctx)
+		assert.NoError(t, err)
+	})
+
+	assert.Equal(t, int32(1), j.ErrorCount)
+	assert.Contains(t, j.LastError.String, "the panic msg")
+
+	err = j.Heartbeat(ctx)
+	require.NoError(t, err)
+	err = j.Done(ctx)
+	require.NoError(t, err)
+	assert.ErrorIs(t, j.Heartbeat(ctx), adapter.ErrTxClosed)
+}
+
+func TestWorker_HeartbeatStopsOnTx(t *testing.T) {
+	ctx := context.Background()
+
+	c, err := NewClient(nil)
+	require.NoError(t, err)
+
+	var heartbeats atomic.Int64
+	tx := new(adapterTesting.Tx)
+	tx.Queryable.On("Exec", mock.Anything, mock.MatchedBy(func(query string) bool {
+		return strings.HasPrefix(query, "UPDATE gue_jobs SET updated_at")
+	}), mock.Anything).Run(func(mock.Arguments) { heartbeats.Add(1) }).Return(nil, nil)
+	tx.Queryable.On("Exec", mock.Anything, mock.Anything, mock.Anything).Return(nil, nil)
+	tx.Mock.On("Commit", mock.Anything).Return(nil).Once()
+
+	var beforeTx, afterTx int64
+	wm := WorkMap{"MyJob": func(ctx context.Context, j *Job) error {
+		require.Eventually(t, func() bool { return heartbeats.Load() > 0 }, 5*time.Second, time.Millisecond)
+
+		// heartbeat that is in progress finishes before the transaction is handed out
+		require.NotNil(t, j.Tx())
+		beforeTx = heartbeats.Load()
+		time.Sleep(50 * time.Millisecond)
+		afterTx = heartbeats.Load()
+		return nil
+	}}
+
+	w, err := NewWorker(
+		c, wm,
+		WithWorkerHeartbeat(time.Millisecond),
+		withWorkerPollFunc(func(context.Context, string) (*Job, error) {
+			return &Job{Type: "MyJob", tx: tx, client: c, backoff: c.backoff, logger: c.logger, now: c.now}, nil
+		}),
+	)
+	require.NoError(t, err)
+
+	outcome, err := w.WorkOneOutcome(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, JobOutcomeSucceeded, outcome)
+	assert.Equal(t, beforeTx, afterTx, "heartbeat must not use the transaction taken by the handler")
+	tx.Mock.AssertExpectations(t)
+}
+
+func TestWorker_PauseResume(t *testing.T) {
+	w, err := NewWorker(nil, dummyWM, WithWorkerPollInterval(10*time.Millisecond))
+	require.NoError(t, err)
+
+	var polled atomic.Int64
+	w.pollFunc = func(context.Context, string) (*Job, error) {
+		polled.Add(1)
+		return nil, nil
+	}
+
+	assert.Equal(t, WorkerStatusStopped, w.Status())
+	assert.False(t, w.Running())
+
+	// worker paused before it is run starts paused
+	w.Pause()
+	w.Pause()
+	assert.Equal(t, WorkerStatusStopped, w.Status())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var grp errgroup.Group
+	grp.Go(func() error {
+		return w.Run(ctx)
+	})
+
+	require.Eventually(t, func() bool {
+		return w.Status() == WorkerStatusPaused
+	}, time.Second, time.Millisecond)
+	assert.False(t, w.Running())
+
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, int64(0), polled.Load())
+
+	w.Resume()
+	w.Resume()
+	assert.True(t, w.Running())
+	require.Eventually(t, func() bool {
+		return polled.Load() > 0
+	}, time.Second, time.Millisecond)
+
+	w.Pause()
+	assert.Equal(t, WorkerStatusPaused, w.Status())
+	// let the worker finish current iteration
+	time.Sleep(50 * time.Millisecond)
+	polledWhenPaused := polled.Load()
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, polledWhenPaused, polled.Load())
+
+	// shutdown while paused does not block
+	cancel()
+	require.NoError(t, grp.Wait())
+	assert.Equal(t, WorkerStatusStopped, w.Status())
+}
+
+func TestWorkerPool_PauseResume(t *testing.T) {
+	pool, err := NewWorkerPool(nil, dummyWM, 3, WithPoolPollInterval(10*time.Millisecond))
+	require.NoError(t, err)
+
+	var polled atomic.Int64
+	for _, w := range pool.workers {
+		w.pollFunc = func(context.Context, string) (*Job, error) {
+			polled.Add(1)
+			return nil, nil
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var grp errgroup.Group
+	grp.Go(func() error {
+		return pool.Run(ctx)
+	})
+
+	require.Eventually(t, pool.Running, time.Second, time.Millisecond)
+	require.Eventually(t, func() bool {
+		return polled.Load() > 0
+	}, time.Second, time.Millisecond)
+
+	var pauseGrp sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		pauseGrp.Add(1)
+		go func() {
+			defer pauseGrp.Done()
+			pool.Pause()
+		}()
+	}
+	pauseGrp.Wait()
+
+	assert.Equal(t, WorkerStatusPaused, pool.Status())
+	for _, w := range pool.workers {
+		assert.Equal(t, WorkerStatusPaused, w.Status())
+	}
+
+	// let the workers finish current iteration
+	time.Sleep(50 * time.Millisecond)
+	polledWhenPaused := polled.Load()
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, polledWhenPaused, polled.Load())
+
+	pool.Resume()
+	assert.True(t, pool.Running())
+	require.Eventually(t, func() bool {
+		return polled.Load() > polledWhenPaused
+	}, time.Second, time.Millisecond)
+
+	pool.Pause()
+	cancel()
+	require.NoError(t, grp.Wait())
+	assert.Equal(t, WorkerStatusStopped, pool.Status())
+}
+
+// withWorkerPollFunc overrides worker poll function, so the worker loop can be tested without the DB.
+func withWorkerPollFunc(f pollFunc) WorkerOption {
+	return func(w *Worker) {
+		w.pollFunc = f
+	}
+}
+
+func TestWorkerPool_Resize(t *testing.T) {
+	pool, err := NewWorkerPool(nil, dummyWM, 2, WithPoolID("pool"))
+	require.NoError(t, err)
+	assert.Equal(t, 2, pool.Size())
+
+	err = pool.Resize(-1)
+	require.Error(t, err)
+	assert.Equal(t, 2, pool.Size())
+
+	require.NoError(t, pool.Resize(4))
+	assert.Equal(t, 4, pool.Size())
+	for i, w := range pool.workers {
+		assert.Equal(t, fmt.Sprintf("pool/worker-%d", i), w.id)
+		assert.Equal(t, WorkerStatusStopped, w.Status())
+	}
+
+	require.NoError(t, pool.Resize(1))
+	assert.Equal(t, 1, pool.Size())
+
+	pool.Pause()
+	require.NoError(t, pool.Resize(2))
+	assert.True(t, pool.workers[1].paused)
+
+	require.NoError(t, pool.Resize(0))
+	assert.Equal(t, 0, pool.Size())
+	assert.False(t, pool.WorkOne(context.Background()))
+	assert.Equal(t, 0, pool.Step(context.Background()))
+}
+
+func TestWorkerPool_ResizeRunning(t *testing.T) {
+	var polled sync.Map
+	poll := func(ctx context.Context, _ string) (*Job, error) {
+		polled.Store(GetWorkerID(ctx), true)
+		return nil, nil
+	}
+
+	pool, err := NewWorkerPool(
+		nil, dummyWM, 1,
+		WithPoolID("pool"),
+		WithPoolPollInterval(time.Millisecond),
+		WithPoolWorkerOptions(withWorkerPollFunc(poll)),
+	)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var grp errgroup.Group
+	grp.Go(func() error {
+		return pool.Run(ctx)
+	})
+	require.Eventually(t, pool.Running, time.Second, time.Millisecond)
+
+	require.NoError(t, pool.Resize(3))
+	assert.Equal(t, 3, pool.Size())
+	for i := 0; i < 3; i++ {
+		workerID := fmt.Sprintf("pool/worker-%d", i)
+		require.Eventually(t, func() bool {
+			_, ok := polled.Load(workerID)
+			return ok
+		}, time.Second, time.Millisecond, workerID)
+	}
+
+	surplus := pool.workers[1:]
+	require.NoError(t, pool.Resize(1))
+	assert.Equal(t, 1, pool.Size())
+	for _, w := range surplus {
+		require.Eventually(t, func() bool {
+			return w.Status() == WorkerStatusStopped
+		}, time.Second, time.Millisecond, w.id)
+	}
+	assert.True(t, pool.workers[0].Running())
+
+	// resizing concurrently with the shutdown must not break anything, shutdown wins
+	var resizeGrp sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		resizeGrp.Add(1)
+		go func(size int) {
+			defer resizeGrp.Done()
+			assert.NoError(t, pool.Resize(size))
+		}(i % 4)
+	}
+	cancel()
+	resizeGrp.Wait()
+	require.NoError(t, grp.Wait())
+
+	// workers added after the shutdown are never started
+	require.NoError(t, pool.Resize(5))
+	time.Sleep(10 * time.Millisecond)
+	for _, w := range pool.workers {
+		assert.Equal(t, WorkerStatusStopped, w.Status())
+	}
+	assert.Equal(t, WorkerStatusStopped, pool.Status())
+}
+
+func TestWorkerPool_ResizeReusedIdx(t *testing.T) {
+	c, err := NewClient(nil)
+	require.NoError(t, err)
+
+	started, release := make(chan struct{}), make(chan struct{})
+	wm := WorkMap{"MyJob": func(ctx context.Context, j *Job) error {
+		close(started)
+		<-release
+		return nil
+	}}
+
+	var (
+		polls     sync.Map
+		jobWorker = "pool/worker-1"
+		jobPoll   = idlePollFunc(t, c, true)
+	)
+	poll := func(ctx context.Context, queue string) (*Job, error) {
+		id := GetWorkerID(ctx)
+		n, _ := polls.LoadOrStore(id, new(atomic.Int64))
+		if n.(*atomic.Int64).Add(1) == 1 && id == jobWorker {
+			return jobPoll(ctx, queue)
+		}
+		return nil, nil
+	}
+	pollsOf := func(id string) int64 {
+		n, ok := polls.Load(id)
+		if !ok {
+			return 0
+		}
+		return n.(*atomic.Int64).Load()
+	}
+
+	pool, err := NewWorkerPool(
+		c, wm, 2,
+		WithPoolID("pool"),
+		WithPoolPollInterval(time.Millisecond),
+		WithPoolWorkerOptions(withWorkerPollFunc(poll)),
+	)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var grp errgroup.Group
+	grp.Go(func() error {
+		return pool.Run(ctx)
+	})
+	<-started
+
+	// the worker is stopped while working the job and replaced with the new one with the same ID
+	stopped := pool.workers[1]
+	require.NoError(t, pool.Resize(1))
+	require.NoError(t, pool.Resize(2))
+	replaced := pool.workers[1]
+	require.NotSame(t, stopped, replaced)
+
+	pollsBefore := pollsOf(jobWorker)
+	time.Sleep(20 * time.Millisecond)
+	assert.Equal(t, pollsBefore, pollsOf(jobWorker), "new worker must not run until the stopped one exits")
+	assert.Equal(t, WorkerStatusStopped, replaced.Status())
+
+	close(release)
+	require.Eventually(t, func() bool {
+		return stopped.Status() == WorkerStatusStopped && replaced.Running()
+	}, time.Second, time.Millisecond)
+	require.Eventually(t, func() bool {
+		return pollsOf(jobWorker) > pollsBefore
+	}, time.Second, time.Millisecond)
+
+	cancel()
+	require.NoError(t, grp.Wait())
+}
+
+func TestWorker_BatchSize(t *testing.T) {
+	for name, openFunc := range adapterTesting.AllAdaptersOpenTestPool {
+		t.Run(name, func(t *testing.T) {
+			testWorkerBatchSize(t, openFunc(t))
+		})
+	}
+}
+
+func testWorkerBatchSize(t *testing.T, connPool adapter.ConnPool) {
+	ctx := context.Background()
+
+	c, err := NewClient(connPool)
+	require.NoError(t, err)
+
+	var worked []string
+	wm := WorkMap{
+		"ok-job": func(ctx context.Context, j *Job) error {
+			worked = append(worked, string(j.Args))
+			return nil
+		},
+		"panicking-job": func(ctx context.Context, j *Job) error {
+			worked = append(worked, string(j.Args))
+			panic("the panic msg")
+		},
+	}
+
+	jobDoneHook := new(mockHook)
+	w, err := NewWorker(c, wm, WithWorkerBatchSize(5), WithWorkerHooksJobDone(jobDoneHook.handler))
+	require.NoError(t, err)
+
+	assert.False(t, w.Step(ctx))
+
+	require.NoError(t, c.Enqueue(ctx, &Job{Type: "ok-job", Args: []byte("1")}))
+	panickingJob := Job{Type: "panicking-job", Args: []byte("2")}
+	require.NoError(t, c.Enqueue(ctx, &panickingJob))
+	require.NoError(t, c.Enqueue(ctx, &Job{Type: "ok-job", Args: []byte("3")}))
+
+	// single step works the whole batch
+	assert.True(t, w.Step(ctx))
+	assert.ElementsMatch(t, []string{"1", "2", "3"}, worked)
+	assert.Equal(t, 3, jobDoneHook.called)
+
+	// panicked job is errored, the others are deleted
+	var count int
+	err = connPool.QueryRow(ctx, `SELECT COUNT(1) FROM gue_jobs`).Scan(&count)
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	j, err := c.LockJobByID(ctx, panickingJob.ID)
+	require.NoError(t, err)
+	require.NotNil(t, j)
+
+	t.Cleanup(func() {
+		err := j.Done(ctx)
+		assert.NoError(t, err)
+	})
+
+	assert.Equal(t, int32(1), j.ErrorCount)
+	assert.Contains(t, j.LastError.String, "the panic msg")
+}
+
+func TestWorker_BatchSizeNoJobs(t *testing.T) {
+	rows := new(adapterTesting.Rows)
+	rows.On("Next").Return(false)
+	rows.On("Err").Return(nil)
+
+	tx := new(adapterTesting.Tx)
+	tx.Queryable.On("Query", mock.Anything, mock.Anything, mock.Anything).Return(rows, nil)
+	tx.Mock.On("Rollback", mock.Anything).Return(nil)
+
+	connPool := new(adapterTesting.ConnPool)
+	connPool.Mock.On("Begin", mock.Anything).Return(tx, nil)
+
+	c, err := NewClient(connPool)
+	require.NoError(t, err)
+
+	w, err := NewWorker(c, dummyWM, WithWorkerBatchSize(3))
+	require.NoError(t, err)
+
+	// empty queue is the regular batch lock result
+	assert.False(t, w.Step(context.Background()))
+	tx.Mock.AssertExpectations(t)
+	connPool.Mock.AssertExpectations(t)
+}
+
+func TestWorker_BatchSizeAbortedTx(t *testing.T) {
+	for name, openFunc := range adapterTesting.AllAdaptersOpenTestPool {
+		t.Run(name, func(t *testing.T) {
+			testWorkerBatchSizeAbortedTx(t, openFunc(t))
+		})
+	}
+}
+
+func testWorkerBatchSizeAbortedTx(t *testing.T, connPool adapter.ConnPool) {
+	ctx := context.Background()
+
+	c, err := NewClient(connPool)
+	require.NoError(t, err)
+
+	var worked []string
+	wm := WorkMap{
+		"ok-job": func(ctx context.Context, j *Job) error {
+			worked = append(worked, string(j.Args))
+			return nil
+		},
+		"aborting-job": func(ctx context.Context, j *Job) error {
+			worked = append(worked, string(j.Args))
+			// failed query aborts the shared batch transaction
+			_, err := j.Tx().Exec(ctx, `SELECT 1/0`)
+			return err
+		},
+	}
+
+	sink, events := NewJobEventChannel(3)
+	w, err := NewWorker(c, wm, WithWorkerBatchSize(3), WithWorkerEventSink(sink))
+	require.NoError(t, err)
+
+	now := time.Now()
+	abortingJob := Job{Type: "aborting-job", Args: []byte("2"), RunAt: now.Add(-2 * time.Second)}
+	require.NoError(t, c.Enqueue(ctx, &Job{Type: "ok-job", Args: []byte("1"), RunAt: now.Add(-3 * time.Second)}))
+	require.NoError(t, c.Enqueue(ctx, &abortingJob))
+	require.NoError(t, c.Enqueue(ctx, &Job{Type: "ok-job", Args: []byte("3"), RunAt: now.Add(-time.Second)}))
+
+	assert.True(t, w.Step(ctx))
+	assert.Equal(t, []string{"1", "2", "3"}, worked)
+
+	// events are sent once the batch transaction is committed
+	require.Len(t, events, 3)
+	assert.Equal(t, JobOutcomeSucceeded, (<-events).Outcome)
+	assert.Equal(t, JobOutcomeErrored, (<-events).Outcome)
+	assert.Equal(t, JobOutcomeSucceeded, (<-events).Outcome)
+
+	// only the aborting job changes are rolled back, it is released back to the queue as is,
+	// while the jobs worked before and after it are deleted
+	var ids []string
+	rows, err := connPool.Query(ctx, `SELECT job_id FROM gue_jobs`)
+	require.NoError(t, err)
+	for rows.Next() {
+		var id string
+		require.NoError(t, rows.Scan(&id))
+		ids = append(ids, id)
+	}
+	require.NoError(t, rows.Err())
+	assert.Equal(t, []string{abortingJob.ID.String()}, ids)
+
+	j, err := c.LockJobByID(ctx, abortingJob.ID)
+	require.NoError(t, err)
+	require.NotNil(t, j)
+
+	t.Cleanup(func() {
+		err := j.Done(ctx)
+		assert.NoError(t, err)
+	})
+
+	assert.Equal(t, int32(0), j.ErrorCount)
+}
+
+func TestWorker_BatchSizeStopped(t *testing.T) {
+	for name, openFunc := range adapterTesting.AllAdaptersOpenTestPool {
+		t.Run(name, func(t *testing.T) {
+			testWorkerBatchSizeStopped(t, openFunc(t))
+		})
+	}
+}
+
+func testWorkerBatchSizeStopped(t *testing.T, connPool adapter.ConnPool) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	c, err := NewClient(connPool)
+	require.NoError(t, err)
+
+	var worked []string
+	wm := WorkMap{
+		"stopping-job": func(ctx context.Context, j *Job) error {
+			worked = append(worked, string(j.Args))
+			// worker is stopped while the batch is being worked
+			cancel()
+			return nil
+		},
+	}
+
+	w, err := NewWorker(c, wm, WithWorkerBatchSize(3), WithWorkerGracefulShutdown(nil))
+	require.NoError(t, err)
+
+	now := time.Now()
+	for i := 1; i <= 3; i++ {
+		require.NoError(t, c.Enqueue(ctx, &Job{
+			Type:  "stopping-job",
+			Args:  []byte(strconv.Itoa(i)),
+			RunAt: now.Add(time.Duration(i-4) * time.Second),
+		}))
+	}
+
+	assert.True(t, w.Step(ctx))
+	assert.Equal(t, []string{"1"}, worked)
+
+	// the rest of the batch is released back to the queue without being worked or marked as errored
+	var count, errorCount int
+	err = connPool.QueryRow(
+		context.Background(),
+		`SELECT COUNT(1), COALESCE(SUM(error_count), 0) FROM gue_jobs WHERE job_type = 'stopping-job'`,
+	).Scan(&count, &errorCount)
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+	assert.Equal(t, 0, errorCount)
+}
+
+func TestWorker_Drain(t *testing.T) {
+	var polled atomic.Int64
+	poll := func(context.Context, string) (*Job, error) {
+		polled.Add(1)
+		return nil, nil
+	}
+
+	w, err := NewWorker(
+		nil, dummyWM,
+		WithWorkerPollInterval(time.Millisecond),
+		WithWorkerDrainEmptyPolls(3),
+		withWorkerPollFunc(poll),
+	)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var grp errgroup.Group
+	grp.Go(func() error {
+		return w.Run(ctx)
+	})
+
+	require.Eventually(t, func() bool {
+		return polled.Load() > 5
+	}, time.Second, time.Millisecond)
+
+	// worker exits on its own after the required number of empty polls once draining
+	w.Drain()
+	require.NoError(t, grp.Wait())
+	assert.Equal(t, WorkerStatusStopped, w.Status())
+
+	// drained before run worker exits after the empty polls
+	polled.Store(0)
+	w.Drain()
+	require.NoError(t, w.Run(ctx))
+	assert.Equal(t, int64(3), polled.Load())
+}
+
+func TestWorker_DrainShutdown(t *testing.T) {
+	w, err := NewWorker(
+		nil, dummyWM,
+		WithWorkerPollInterval(time.Hour),
+		WithWorkerDrainEmptyPolls(3),
+		withWorkerPollFunc(func(context.Context, string) (*Job, error) { return nil, nil }),
+	)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	var grp errgroup.Group
+	grp.Go(func() error {
+		return w.Run(ctx)
+	})
+
+	// draining worker waits for the next poll, but context cancellation stops it immediately
+	w.Drain()
+	require.Eventually(t, w.Running, time.Second, time.Millisecond)
+	cancel()
+	require.NoError(t, grp.Wait())
+	assert.Equal(t, WorkerStatusStopped, w.Status())
+}
+
+func TestWorkerPool_Drain(t *testing.T) {
+	pool, err := NewWorkerPool(
+		nil, dummyWM, 3,
+		WithPoolPollInterval(time.Millisecond),
+		WithPoolDrainEmptyPolls(2),
+		WithPoolWorkerOptions(withWorkerPollFunc(func(context.Context, string) (*Job, error) { return nil, nil })),
+	)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var grp errgroup.Group
+	grp.Go(func() error {
+		return pool.Run(ctx)
+	})
+	require.Eventually(t, pool.Running, time.Second, time.Millisecond)
+
+	pool.Drain()
+	require.NoError(t, grp.Wait())
+	assert.Equal(t, WorkerStatusStopped, pool.Status())
+	for _, w := range pool.workers {
+		assert.Equal(t, WorkerStatusStopped, w.Status())
+	}
+}
+
+func TestWorkerPool_DrainQueue(t *testing.T) {
+	for name, openFunc := range adapterTesting.AllAdaptersOpenTestPool {
+		t.Run(name, func(t *testing.T) {
+			testWorkerPoolDrainQueue(t, openFunc(t))
+		})
+	}
+}
+
+func testWorkerPoolDrainQueue(t *testing.T, connPool adapter.ConnPool) {
+	ctx := context.Background()
+
+	c, err := NewClient(connPool)
+	require.NoError(t, err)
+
+	var worked atomic.Int64
+	wm := WorkMap{
+		"MyJob": func(ctx context.Context, j *Job) error {
+			worked.Add(1)
+			return nil
+		},
+	}
+
+	const jobsCount = 10
+	for i := 0; i < jobsCount; i++ {
+		require.NoError(t, c.Enqueue(ctx, &Job{Type: "MyJob"}))
+	}
+
+	pool, err := NewWorkerPool(c, wm, 2, WithPoolPollInterval(10*time.Millisecond))
+	require.NoError(t, err)
+
+	// pool drained before it is run works the whole queue and exits without context cancellation
+	pool.Drain()
+	require.NoError(t, pool.Run(ctx))
+	assert.Equal(t, int64(jobsCount), worked.Load())
+
+	var count int
+	err = connPool.QueryRow(ctx, `SELECT COUNT(1) FROM gue_jobs`).Scan(&count)
+	require.NoError(t, err)
+	assert.Equal(t, 0, count)
+}
+
+func TestWorker_TypeRateLimit(t *testing.T) {
+	for name, openFunc := range adapterTesting.AllAdaptersOpenTestPool {
+		t.Run(name, func(t *testing.T) {
+			testWorkerTypeRateLimit(t, openFunc(t))
+		})
+	}
+}
+
+func testWorkerTypeRateLimit(t *testing.T, connPool adapter.ConnPool) {
+	ctx := context.Background()
+
+	c, err := NewClient(connPool)
+	require.NoError(t, err)
+
+	var limitedWorked, freeWorked int
+	wm := WorkMap{
+		"limited-job": func(ctx context.Context, j *Job) error {
+			limitedWorked++
+			return nil
+		},
+		"free-job": func(ctx context.Context, j *Job) error {
+			freeWorked++
+			return nil
+		},
+	}
+
+	const jobsCount = 5
+	for i := 0; i < jobsCount; i++ {
+		require.NoError(t, c.Enqueue(ctx, &Job{Type: "limited-job"}))
+		require.NoError(t, c.Enqueue(ctx, &Job{Type: "free-job"}))
+	}
+
+	jobDoneHook := new(mockHook)
+	w, err := NewWorker(
+		c, wm,
+		WithWorkerTypeRateLimit("limited-job", 0.001, 1),
+		WithWorkerLimitedJobDelay(time.Hour),
+		WithWorkerHooksJobDone(jobDoneHook.handler),
+	)
+	require.NoError(t, err)
+
+	for w.WorkOne(ctx) {
+	}
+
+	// worker does not block on the limited jobs and works all the jobs of the other type
+	assert.Equal(t, 1, limitedWorked)
+	assert.Equal(t, jobsCount, freeWorked)
+	assert.Equal(t, jobsCount+1, jobDoneHook.called)
+
+	// limited jobs are postponed without being marked as failed
+	var count, errorCount int
+	err = connPool.QueryRow(
+		ctx,
+		`SELECT COUNT(1), SUM(error_count) FROM gue_jobs WHERE job_type = 'limited-job' AND run_at > now()`,
+	).Scan(&count, &errorCount)
+	require.NoError(t, err)
+	assert.Equal(t, jobsCount-1, count)
+	assert.Equal(t, 0, errorCount)
+}
+
+func TestWorker_TypeLimiterDedupKey(t *testing.T) {
+	ctx := context.Background()
+
+	c, err := NewClient(nil)
+	require.NoError(t, err)
+
+	set := NewDedupKeySet()
+	acquired, err := set.Acquire(ctx, "MyJob")
+	require.NoError(t, err)
+	require.True(t, acquired)
+
+	w, err := NewWorker(
+		c, WorkMap{"MyJob": func(ctx context.Context, j *Job) error { return nil }},
+		WithWorkerTypeRateLimit("MyJob", 0.001, 1),
+		WithWorkerDedupKey(func(j *Job) string { return j.Type }),
+		WithWorkerDedupKeySet(set),
+		withWorkerPollFunc(idlePollFunc(t, c, true, true)),
+	)
+	require.NoError(t, err)
+
+	// duplicate job is postponed without consuming the only limiter token
+	outcome, err := w.WorkOneOutcome(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, JobOutcomePostponed, outcome)
+
+	require.NoError(t, set.Release(ctx, "MyJob"))
+
+	outcome, err = w.WorkOneOutcome(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, JobOutcomeSucceeded, outcome)
+}
+
+func TestWorker_PollJitter(t *testing.T) {
+	assert.Equal(t, time.Duration(0), randomDuration(0))
+	assert.Equal(t, time.Duration(0), randomDuration(-time.Second))
+
+	w, err := NewWorker(nil, dummyWM, WithWorkerPollInterval(time.Second), WithWorkerPollJitter(1))
+	require.NoError(t, err)
+	assert.Equal(t, 500*time.Millisecond, w.maxPollJitter())
+
+	for i := 0; i < 100; i++ {
+		d := randomDuration(w.maxPollJitter())
+		assert.GreaterOrEqual(t, d, time.Duration(0))
+		assert.Less(t, d, 500*time.Millisecond)
+	}
+}
+
+func TestWorker_IdleBackoff(t *testing.T) {
+	wWOutBackoff, err := NewWorker(nil, dummyWM, WithWorkerPollInterval(time.Second))
+	require.NoError(t, err)
+	assert.Equal(t, time.Second, wWOutBackoff.nextIdleInterval(time.Second))
+
+	// max interval that is not greater than the poll interval disables backoff
+	wShortBackoff, err := NewWorker(nil, dummyWM, WithWorkerPollInterval(time.Second), WithWorkerIdleBackoff(time.Second))
+	require.NoError(t, err)
+	assert.Equal(t, time.Second, wShortBackoff.nextIdleInterval(time.Second))
+
+	w, err := NewWorker(nil, dummyWM, WithWorkerPollInterval(time.Second), WithWorkerIdleBackoff(time.Minute))
+	require.NoError(t, err)
+
+	var intervals []time.Duration
+	interval := w.interval
+	for i := 0; i < 8; i++ {
+		interval = w.nextIdleInterval(interval)
+		intervals = append(intervals, interval)
+	}
+	assert.Equal(t, []time.Duration{
+		2 * time.Second,
+		4 * time.Second,
+		8 * time.Second,
+		16 * time.Second,
+		32 * time.Second,
+		time.Minute,
+		time.Minute,
+		time.Minute,
+	}, intervals)
+}
+
+func TestWorker_IdleBackoffReset(t *testing.T) {
+	c, err := NewClient(nil)
+	require.NoError(t, err)
+
+	var (
+		mu    sync.Mutex
+		polls []time.Time
+	)
+	// jobs are found only on the first and the fifth polls
+	poll := idlePollFunc(t, c, true, false, false, false, true, false)
+	w, err := NewWorker(
+		c, dummyWM,
+		WithWorkerPollInterval(10*time.Millisecond),
+		WithWorkerPollJitter(0),
+		WithWorkerIdleBackoff(time.Second),
+		withWorkerPollFunc(func(ctx context.Context, queue string) (*Job, error) {
+			mu.Lock()
+			polls = append(polls, time.Now())
+			mu.Unlock()
+			return poll(ctx, queue)
+		}),
+	)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var grp errgroup.Group
+	grp.Go(func() error {
+		return w.Run(ctx)
+	})
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(polls) >= 7
+	}, 5*time.Second, time.Millisecond)
+
+	cancel()
+	require.NoError(t, grp.Wait())
+
+	mu.Lock()
+	defer mu.Unlock()
+	// the poll after the job was found is immediate, then the interval doubles on every empty poll
+	// and is reset once the job is found
+	for i, minInterval := range []time.Duration{0, 10, 20, 40, 0, 10} {
+		assert.GreaterOrEqual(t, polls[i+1].Sub(polls[i]), minInterval*time.Millisecond, "poll %d", i+1)
+	}
+	// without the reset the interval would be 80ms
+	assert.Less(t, polls[6].Sub(polls[5]), 60*time.Millisecond)
+}
+
+func TestWorker_LoopBackoff(t *testing.T) {
+	wWOutBackoff, err := NewWorker(nil, dummyWM, WithWorkerPollInterval(time.Second))
+	require.NoError(t, err)
+	assert.Equal(t, time.Second, wWOutBackoff.lockErrorInterval(5))
+
+	// max interval that is not greater than the poll interval disables backoff
+	wShortBackoff, err := NewWorker(nil, dummyWM, WithWorkerPollInterval(time.Second), WithWorkerLoopBackoff(time.Second))
+	require.NoError(t, err)
+	assert.Equal(t, time.Second, wShortBackoff.lockErrorInterval(5))
+
+	w, err := NewWorker(nil, dummyWM, WithWorkerPollInterval(time.Second), WithWorkerLoopBackoff(time.Minute))
+	require.NoError(t, err)
+
+	var intervals []time.Duration
+	for i := 1; i <= 8; i++ {
+		intervals = append(intervals, w.lockErrorInterval(i))
+	}
+	assert.Equal(t, []time.Duration{
+		time.Second,
+		2 * time.Second,
+		4 * time.Second,
+		8 * time.Second,
+		16 * time.Second,
+		32 * time.Second,
+		time.Minute,
+		time.Minute,
+	}, intervals)
+}
+
+func TestWorker_LoopBackoffReset(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		polls []time.Time
+	)
+	// lock fails on all the polls except for the fourth one that finds no job
+	w, err := NewWorker(
+		nil, dummyWM,
+		WithWorkerPollInterval(10*time.Millisecond),
+		WithWorkerPollJitter(0),
+		WithWorkerLoopBackoff(time.Second),
+		withWorkerPollFunc(func(ctx context.Context, queue string) (*Job, error) {
+			mu.Lock()
+			defer mu.Unlock()
+
+			polls = append(polls, time.Now())
+			if len(polls) == 4 {
+				return nil, nil
+			}
+			return nil, errors.New("db is down")
+		}),
+	)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var grp errgroup.Group
+	grp.Go(func() error {
+		return w.Run(ctx)
+	})
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(polls) >= 6
+	}, 5*time.Second, time.Millisecond)
+
+	cancel()
+	require.NoError(t, grp.Wait())
+
+	mu.Lock()
+	defer mu.Unlock()
+	// the interval doubles on every lock error and is reset once the lock attempt succeeds
+	for i, minInterval := range []time.Duration{10, 20, 40, 10, 10} {
+		assert.GreaterOrEqual(t, polls[i+1].Sub(polls[i]), minInterval*time.Millisecond, "poll %d", i+1)
+	}
+	// without the reset the interval would be 80ms
+	assert.Less(t, polls[4].Sub(polls[3]), 60*time.Millisecond)
+}
+
+func TestWorker_LoopBackoffInterrupted(t *testing.T) {
+	polled := make(chan struct{}, 1)
+	w, err := NewWorker(
+		nil, dummyWM,
+		WithWorkerPollInterval(time.Hour),
+		WithWorkerLoopBackoff(24*time.Hour),
+		withWorkerPollFunc(func(ctx context.Context, queue string) (*Job, error) {
+			select {
+			case polled <- struct{}{}:
+			default:
+			}
+			return nil, errors.New("db is down")
+		}),
+	)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var grp errgroup.Group
+	grp.Go(func() error {
+		return w.Run(ctx)
+	})
+
+	<-polled
+	cancel()
+
+	done := make(chan error)
+	go func() {
+		done <- grp.Wait()
+	}()
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("worker did not stop while backing off")
+	}
+}
+
+func TestWorkerPool_QueueCounts(t *testing.T) {
+	for name, openFunc := range adapterTesting.AllAdaptersOpenTestPool {
+		t.Run(name, func(t *testing.T) {
+			testWorkerPoolQueueCounts(t, openFunc(t))
+		})
+	}
+}
+
+func testWorkerPoolQueueCounts(t *testing.T, connPool adapter.ConnPool) {
+	ctx := context.Background()
+
+	c, err := NewClient(connPool)
+	require.NoError(t, err)
+
+	var (
+		mu     sync.Mutex
+		worked = make(map[string]int)
+	)
+	wm := WorkMap{
+		"MyJob": func(ctx context.Context, j *Job) error {
+			mu.Lock()
+			defer mu.Unlock()
+			worked[j.Queue]++
+			return nil
+		},
+	}
+
+	for _, queue := range []string{"a", "a", "b", "b", "b", "c"} {
+		require.NoError(t, c.Enqueue(ctx, &Job{Type: "MyJob", Queue: queue}))
+	}
+
+	pool, err := NewWorkerPool(
+		c, wm, 1,
+		WithPoolPollInterval(10*time.Millisecond),
+		WithPoolQueueCounts(map[string]int{"a": 1, "b": 2}),
+	)
+	require.NoError(t, err)
+
+	pool.Drain()
+	require.NoError(t, pool.Run(ctx))
+
+	// jobs from the queue not covered by the pool are not worked
+	assert.Equal(t, map[string]int{"a": 2, "b": 3}, worked)
+
+	j, err := c.LockJob(ctx, "c")
+	require.NoError(t, err)
+	require.NotNil(t, j)
+
+	t.Cleanup(func() {
+		err := j.Done(ctx)
+		assert.NoError(t, err)
+	})
+}
+
+func TestWorkerWorkOneUnknownTypeRecipes(t *testing.T) {
+	for name, openFunc := range adapterTesting.AllAdaptersOpenTestPool {
+		t.Run(name, func(t *testing.T) {
+			testWorkerWorkOneUnknownTypeRecipes(t, openFunc(t))
+		})
+	}
+}
+
+func testWorkerWorkOneUnknownTypeRecipes(t *testing.T, connPool adapter.ConnPool) {
+	ctx := context.Background()
+
+	c, err := NewClient(connPool)
+	require.NoError(t, err)
+
+	t.Run("skip", func(t *testing.T) {
+		w, err := NewWorker(
+			c, WorkMap{},
+			WithWorkerQueue("unknown-skip"),
+			WithWorkerUnknownJobWorkFunc(func(ctx context.Context, j *Job) error {
+				return ErrPostponeJobIn(time.Hour, "unknown job type")
+			}),
+		)
+		require.NoError(t, err)
+
+		job := Job{Type: "NewJobType", Queue: "unknown-skip"}
+		require.NoError(t, c.Enqueue(ctx, &job))
+		assert.True(t, w.WorkOne(ctx))
+
+		j, err := c.LockJobByID(ctx, job.ID)
+		require.NoError(t, err)
+		require.NotNil(t, j)
+
+		t.Cleanup(func() {
+			err := j.Done(ctx)
+			assert.NoError(t, err)
+		})
+
+		assert.Equal(t, int32(0), j.ErrorCount)
+		assert.True(t, j.RunAt.After(time.Now().Add(59*time.Minute)))
+	})
+
+	t.Run("dead-letter", func(t *testing.T) {
+		w, err := NewWorker(
+			c, WorkMap{},
+			WithWorkerQueue("unknown-dl"),
+			WithWorkerUnknownJobWorkFunc(func(ctx context.Context, j *Job) error {
+				return j.EnqueueInTx(ctx, &Job{Type: j.Type, Queue: "dead-letter", Args: j.Args})
+			}),
+		)
+		require.NoError(t, err)
+
+		job := Job{Type: "NewJobType", Queue: "unknown-dl", Args: []byte(`{"id":1}`)}
+		require.NoError(t, c.Enqueue(ctx, &job))
+		assert.True(t, w.WorkOne(ctx))
+
+		j, err := c.LockJobByID(ctx, job.ID)
+		require.ErrorIs(t, err, adapter.ErrNoRows)
+		assert.Nil(t, j)
+
+		jDL, err := c.LockJob(ctx, "dead-letter")
+		require.NoError(t, err)
+		require.NotNil(t, jDL)
+
+		t.Cleanup(func() {
+			err := jDL.Done(ctx)
+			assert.NoError(t, err)
+		})
+
+		assert.Equal(t, "NewJobType", jDL.Type)
+		assert.Equal(t, []byte(`{"id":1}`), jDL.Args)
+	})
+}
+
+func TestWorkerMinPriority(t *testing.T) {
+	for name, openFunc := range adapterTesting.AllAdaptersOpenTestPool {
+		t.Run(name, func(t *testing.T) {
+			testWorkerMinPriority(t, openFunc(t))
+		})
+	}
+}
+
+func testWorkerMinPriority(t *testing.T, connPool adapter.ConnPool) {
+	ctx := context.Background()
+
+	c, err := NewClient(connPool)
+	require.NoError(t, err)
+
+	var worked []string
+	wm := WorkMap{"MyJob": func(ctx context.Context, j *Job) error {
+		worked = append(worked, string(j.Args))
+		return nil
+	}}
+
+	for _, queue := range []string{"priority", "run-at", "batch"} {
+		err = c.EnqueueBatch(ctx, []*Job{
+			{Type: "MyJob", Queue: queue, Priority: JobPriorityLow, Args: []byte("low")},
+			{Type: "MyJob", Queue: queue, Priority: JobPriorityDefault, Args: []byte("default")},
+			{Type: "MyJob", Queue: queue, Priority: JobPriorityHigh, Args: []byte("high")},
+			{Type: "MyJob", Queue: queue, Priority: JobPriorityHighest, Args: []byte("highest")},
+		})
+		require.NoError(t, err)
+	}
+
+	for name, options := range map[string][]WorkerOption{
+		"priority": {WithWorkerQueue("priority")},
+		"run-at":   {WithWorkerQueue("run-at"), WithWorkerPollStrategy(RunAtPollStrategy)},
+		"batch":    {WithWorkerQueue("batch"), WithWorkerBatchSize(10)},
+	} {
+		t.Run(name, func(t *testing.T) {
+			worked = nil
+
+			w, err := NewWorker(c, wm, append(options, WithWorkerMinPriority(JobPriorityHigh))...)
+			require.NoError(t, err)
+
+			for w.Step(ctx) {
+			}
+			assert.ElementsMatch(t, []string{"highest", "high"}, worked)
+
+			// jobs with the lower priority are left for the catch-all worker
+			wAll, err := NewWorker(c, wm, options...)
+			require.NoError(t, err)
+
+			for wAll.Step(ctx) {
+			}
+			assert.ElementsMatch(t, []string{"highest", "high", "default", "low"}, worked)
+		})
+	}
+}
+
+// idlePollFunc returns the poll function that finds a job for every true value in the sequence and no job otherwise.
+func idlePollFunc(t *testing.T, c *Client, sequence ...bool) pollFunc {
+	var idx atomic.Int64
+
+	return func(context.Context, string) (*Job, error) {
+		i := int(idx.Add(1)) - 1
+		if i >= len(sequence) || !sequence[i] {
+			return nil, nil
+		}
+
+		tx := new(adapterTesting.Tx)
+		tx.Queryable.On("Exec", mock.Anything, mock.Anything, mock.Anything).Return(nil, nil)
+		tx.Mock.On("Commit", mock.Anything).Return(nil)
+		t.Cleanup(func() { tx.Mock.AssertExpectations(t) })
+
+		return &Job{Type: "MyJob", tx: tx, client: c, backoff: c.backoff, logger: c.logger, now: c.now}, nil
+	}
+}
+
+func TestWorkerIdleCallbacks(t *testing.T) {
+	ctx := context.Background()
+
+	c, err := NewClient(nil)
+	require.NoError(t, err)
+
+	var transitions []string
+	w, err := NewWorker(
+		c, dummyWM,
+		WithWorkerOnIdle(func() { transitions = append(transitions, "idle") }),
+		WithWorkerOnBusy(func() { transitions = append(transitions, "busy") }),
+		withWorkerPollFunc(idlePollFunc(t, c, false, true, true, false, false, true, false)),
+	)
+	require.NoError(t, err)
+
+	// callbacks are called only on the transitions, not on every poll
+	for i := 0; i < 7; i++ {
+		w.Step(ctx)
+	}
+	assert.Equal(t, []string{"busy", "idle", "busy", "idle"}, transitions)
+}
+
+func TestWorkerIdleCallbacksStopped(t *testing.T) {
+	c, err := NewClient(nil)
+	require.NoError(t, err)
+
+	var (
+		mu          sync.Mutex
+		transitions []string
+	)
+	track := func(transition string) func() {
+		return func() {
+			mu.Lock()
+			defer mu.Unlock()
+			transitions = append(transitions, transition)
+		}
+	}
+
+	w, err := NewWorker(
+		c, dummyWM,
+		WithWorkerPollInterval(time.Millisecond),
+		WithWorkerOnIdle(track("idle")),
+		WithWorkerOnBusy(track("busy")),
+		// the worker keeps finding jobs until it is stopped
+		withWorkerPollFunc(func(ctx context.Context, queue string) (*Job, error) {
+			return idlePollFunc(t, c, true)(ctx, queue)
+		}),
+	)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var grp errgroup.Group
+	grp.Go(func() error {
+		return w.Run(ctx)
+	})
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(transitions) > 0
+	}, time.Second, time.Millisecond)
+
+	cancel()
+	require.NoError(t, grp.Wait())
+
+	// busy worker that is stopped becomes idle
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, "busy", transitions[0])
+	assert.Equal(t, "idle", transitions[len(transitions)-1])
+}
+
+func TestWorkerPoolIdleCallbacks(t *testing.T) {
+	ctx := context.Background()
+
+	c, err := NewClient(nil)
+	require.NoError(t, err)
+
+	var transitions []string
+	pool, err := NewWorkerPool(
+		c, dummyWM, 2,
+		WithPoolOnIdle(func() { transitions = append(transitions, "idle") }),
+		WithPoolOnBusy(func() { transitions = append(transitions, "busy") }),
+	)
+	require.NoError(t, err)
+
+	pool.workers[0].pollFunc = idlePollFunc(t, c, true, true, false)
+	pool.workers[1].pollFunc = idlePollFunc(t, c, false, true, true, false)
+
+	// the first worker becomes busy - the pool becomes busy
+	pool.workers[0].Step(ctx)
+	pool.workers[1].Step(ctx)
+	assert.Equal(t, []string{"busy"}, transitions)
+
+	// the second worker becomes busy - the pool is busy already
+	pool.workers[0].Step(ctx)
+	pool.workers[1].Step(ctx)
+	assert.Equal(t, []string{"busy"}, transitions)
+
+	// the first worker becomes idle - the pool is still busy
+	pool.workers[0].Step(ctx)
+	pool.workers[1].Step(ctx)
+	assert.Equal(t, []string{"busy"}, transitions)
+
+	// all the workers are idle - the pool becomes idle
+	pool.workers[0].Step(ctx)
+	pool.workers[1].Step(ctx)
+	assert.Equal(t, []string{"busy", "idle"}, transitions)
+
+	// per-worker callbacks are not set by the pool ones
+	for _, w := range pool.workers {
+		assert.Nil(t, w.onIdle)
+		assert.Nil(t, w.onBusy)
+	}
+}
+
+func TestWorker_WorkOneOutcome(t *testing.T) {
+	ctx := context.Background()
+
+	c, err := NewClient(nil)
+	require.NoError(t, err)
+
+	errLock := errors.New("could not lock a job")
+	errHandler := errors.New("handler failed")
+	wm := WorkMap{
+		"succeeded": func(ctx context.Context, j *Job) error { return nil },
+		"errored":   func(ctx context.Context, j *Job) error { return errHandler },
+	}
+
+	for name, tc := range map[string]struct {
+		poll    pollFunc
+		outcome JobOutcome
+		err     error
+	}{
+		"no job": {
+			poll:    func(context.Context, string) (*Job, error) { return nil, nil },
+			outcome: JobOutcomeNoJob,
+		},
+		"lock error": {
+			poll:    func(context.Context, string) (*Job, error) { return nil, errLock },
+			outcome: JobOutcomeLockFailed,
+			err:     errLock,
+		},
+		"succeeded": {
+			poll:    typedPollFunc(t, c, "succeeded"),
+			outcome: JobOutcomeSucceeded,
+		},
+		"handler error": {
+			poll:    typedPollFunc(t, c, "errored"),
+			outcome: JobOutcomeErrored,
+			err:     errHandler,
+		},
+		"unknown type": {
+			poll:    typedPollFunc(t, c, "unknown"),
+			outcome: JobOutcomeUnknownType,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			w, err := NewWorker(c, wm, withWorkerPollFunc(tc.poll))
+			require.NoError(t, err)
+
+			outcome, err := w.WorkOneOutcome(ctx)
+			assert.Equal(t, tc.outcome, outcome)
+			switch {
+			case tc.err != nil:
+				assert.ErrorIs(t, err, tc.err)
+			case outcome == JobOutcomeUnknownType:
+				assert.ErrorContains(t, err, "unknown job type")
+			default:
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestWorker_WorkOneResult(t *testing.T) {
+	ctx := context.Background()
+
+	c, err := NewClient(nil)
+	require.NoError(t, err)
+
+	errLock := errors.New("could not lock a job")
+	errHandler := errors.New("handler failed")
+	wm := WorkMap{
+		"succeeded": func(ctx context.Context, j *Job) error {
+			time.Sleep(time.Millisecond)
+			return nil
+		},
+		"errored": func(ctx context.Context, j *Job) error { return errHandler },
+	}
+
+	w, err := NewWorker(c, wm, withWorkerPollFunc(func(context.Context, string) (*Job, error) { return nil, nil }))
+	require.NoError(t, err)
+	res, err := w.WorkOneResult(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, WorkResult{Outcome: JobOutcomeNoJob}, res)
+
+	w, err = NewWorker(c, wm, withWorkerPollFunc(func(context.Context, string) (*Job, error) { return nil, errLock }))
+	require.NoError(t, err)
+	res, err = w.WorkOneResult(ctx)
+	assert.ErrorIs(t, err, errLock)
+	assert.Equal(t, WorkResult{Outcome: JobOutcomeLockFailed, Err: err}, res)
+
+	poll := typedPollFunc(t, c, "succeeded")
+	var lockedID ulid.ULID
+	w, err = NewWorker(c, wm, withWorkerPollFunc(func(ctx context.Context, queue string) (*Job, error) {
+		j, err := poll(ctx, queue)
+		j.ID = ulid.Make()
+		lockedID = j.ID
+		return j, err
+	}))
+	require.NoError(t, err)
+	res, err = w.WorkOneResult(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, lockedID, res.JobID)
+	assert.Equal(t, "succeeded", res.Type)
+	assert.Equal(t, JobOutcomeSucceeded, res.Outcome)
+	assert.NoError(t, res.Err)
+	assert.GreaterOrEqual(t, res.Duration, time.Millisecond)
+	assert.True(t, res.DidWork)
+
+	w, err = NewWorker(c, wm, withWorkerPollFunc(typedPollFunc(t, c, "errored")))
+	require.NoError(t, err)
+	res, err = w.WorkOneResult(ctx)
+	assert.ErrorIs(t, err, errHandler)
+	assert.ErrorIs(t, res.Err, errHandler)
+	assert.Equal(t, "errored", res.Type)
+	assert.Equal(t, JobOutcomeErrored, res.Outcome)
+	assert.True(t, res.DidWork)
+}
+
+func TestWorkerPool_WorkOneResult(t *testing.T) {
+	ctx := context.Background()
+
+	c, err := NewClient(nil)
+	require.NoError(t, err)
+
+	p, err := NewWorkerPool(c, dummyWM, 2, WithPoolJobLocker(jobLockerFunc(idlePollFunc(t, c, true))))
+	require.NoError(t, err)
+
+	res, err := p.WorkOneResult(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, JobOutcomeSucceeded, res.Outcome)
+	assert.Equal(t, "MyJob", res.Type)
+	assert.True(t, res.DidWork)
+
+	res, err = p.WorkOneResult(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, JobOutcomeNoJob, res.Outcome)
+	assert.False(t, res.DidWork)
+}
+
+// typedPollFunc returns the poll function that finds the single job of the given type.
+func typedPollFunc(t *testing.T, c *Client, jobType string) pollFunc {
+	poll := idlePollFunc(t, c, true)
+
+	return func(ctx context.Context, queue string) (*Job, error) {
+		j, err := poll(ctx, queue)
+		if j != nil {
+			j.Type = jobType
+		}
+		return j, err
+	}
+}
+
+func TestWorkerDedupKey(t *testing.T) {
+	ctx := context.Background()
+
+	c, err := NewClient(nil)
+	require.NoError(t, err)
+
+	keyedPollFunc := func(key string) pollFunc {
+		poll := idlePollFunc(t, c, true)
+		return func(ctx context.Context, queue string) (*Job, error) {
+			j, err := poll(ctx, queue)
+			j.Args = []byte(key)
+			return j, err
+		}
+	}
+
+	var startedOnce sync.Once
+	started, release := make(chan struct{}), make(chan struct{})
+	wm := WorkMap{
+		"MyJob": func(ctx context.Context, j *Job) error {
+			switch string(j.Args) {
+			case "blocking":
+				startedOnce.Do(func() { close(started) })
+				<-release
+			case "panicking":
+				panic("boom")
+			}
+			return nil
+		},
+	}
+
+	set := NewDedupKeySet()
+	newWorker := func(key string) *Worker {
+		w, err := NewWorker(
+			c, wm,
+			WithWorkerDedupKey(func(j *Job) string { return string(j.Args) }),
+			WithWorkerDedupKeySet(set),
+			withWorkerPollFunc(keyedPollFunc(key)),
+		)
+		require.NoError(t, err)
+		return w
+	}
+
+	blockingOutcome := make(chan JobOutcome)
+	go func() {
+		outcome, _ := newWorker("blocking").WorkOneOutcome(ctx)
+		blockingOutcome <- outcome
+	}()
+	<-started
+
+	// the same key is being worked by another worker
+	outcome, err := newWorker("blocking").WorkOneOutcome(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, JobOutcomePostponed, outcome)
+
+	outcome, err = newWorker("other").WorkOneOutcome(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, JobOutcomeSucceeded, outcome)
+
+	close(release)
+	assert.Equal(t, JobOutcomeSucceeded, <-blockingOutcome)
+
+	outcome, err = newWorker("blocking").WorkOneOutcome(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, JobOutcomeSucceeded, outcome)
+
+	// key is released even if the handler panics
+	outcome, err = newWorker("panicking").WorkOneOutcome(ctx)
+	require.Error(t, err)
+	assert.Equal(t, JobOutcomePanicked, outcome)
+
+	acquired, err := set.Acquire(ctx, "panicking")
+	require.NoError(t, err)
+	assert.True(t, acquired)
+
+	// jobs with the empty key are not deduplicated
+	emptyKeyWorker, err := NewWorker(
+		c, wm,
+		WithWorkerDedupKey(func(*Job) string { return "" }),
+		withWorkerPollFunc(idlePollFunc(t, c, true)),
+	)
+	require.NoError(t, err)
+	outcome, err = emptyKeyWorker.WorkOneOutcome(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, JobOutcomeSucceeded, outcome)
+}
+
+func TestWorkerWorkMapTx(t *testing.T) {
+	ctx := context.Background()
+
+	c, err := NewClient(nil)
+	require.NoError(t, err)
+
+	errHandler := errors.New("could not write")
+
+	for name, tc := range map[string]struct {
+		handler WorkFuncTx
+		queries []string
+		outcome JobOutcome
+	}{
+		"succeeded": {
+			handler: func(ctx context.Context, tx adapter.Tx, j *Job) error {
+				_, err := tx.Exec(ctx, "INSERT INTO side_effects")
+				return err
+			},
+			queries: []string{"SAVEPOINT gue_work_tx", "INSERT INTO side_effects", "DELETE FROM gue_jobs"},
+			outcome: JobOutcomeSucceeded,
+		},
+		"errored": {
+			handler: func(ctx context.Context, tx adapter.Tx, j *Job) error {
+				if _, err := tx.Exec(ctx, "INSERT INTO side_effects"); err != nil {
+					return err
+				}
+				return errHandler
+			},
+			queries: []string{"SAVEPOINT gue_work_tx", "INSERT INTO side_effects", "ROLLBACK TO SAVEPOINT gue_work_tx", "UPDATE gue_jobs SET error_count"},
+			outcome: JobOutcomeErrored,
+		},
+		"panicked": {
+			handler: func(ctx context.Context, tx adapter.Tx, j *Job) error {
+				if _, err := tx.Exec(ctx, "INSERT INTO side_effects"); err != nil {
+					return err
+				}
+				panic("boom")
+			},
+			queries: []string{"SAVEPOINT gue_work_tx", "INSERT INTO side_effects", "ROLLBACK TO SAVEPOINT gue_work_tx", "UPDATE gue_jobs SET error_count"},
+			outcome: JobOutcomePanicked,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			var queries []string
+			tx := new(adapterTesting.Tx)
+			tx.Queryable.On("Exec", mock.Anything, mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+				queries = append(queries, args.String(1))
+			}).Return(nil, nil)
+			tx.Mock.On("Commit", mock.Anything).Return(nil).Once()
+
+			j := &Job{Type: "MyJobTx", tx: tx, client: c, backoff: c.backoff, logger: c.logger, now: c.now}
+			w, err := NewWorker(
+				c, dummyWM,
+				WithWorkerWorkMapTx(WorkMapTx{"MyJobTx": tc.handler}),
+				withWorkerPollFunc(func(context.Context, string) (*Job, error) { return j, nil }),
+			)
+			require.NoError(t, err)
+
+			outcome, _ := w.WorkOneOutcome(ctx)
+			assert.Equal(t, tc.outcome, outcome)
+			require.Len(t, queries, len(tc.queries))
+			for i, query := range tc.queries {
+				assert.True(t, strings.HasPrefix(queries[i], query), "query %d: %s", i, queries[i])
+			}
+			tx.Mock.AssertExpectations(t)
+		})
+	}
+}
+
+func TestWorkerWorkMapTxHeartbeat(t *testing.T) {
+	ctx := context.Background()
+
+	c, err := NewClient(nil)
+	require.NoError(t, err)
+
+	var (
+		inUse      atomic.Bool
+		concurrent atomic.Bool
+		queries    atomic.Int64
+	)
+	tx := new(adapterTesting.Tx)
+	tx.Queryable.On("Exec", mock.Anything, mock.MatchedBy(func(query string) bool {
+		return strings.HasPrefix(query, "UPDATE gue_jobs SET updated_at")
+	}), mock.Anything).Run(func(mock.Arguments) {
+		t.Error("heartbeat must not be executed for the job worked in the job transaction")
+	}).Return(nil, nil)
+	tx.Queryable.On("Exec", mock.Anything, mock.Anything, mock.Anything).Run(func(mock.Arguments) {
+		// transaction connection can not be used concurrently
+		if !inUse.CompareAndSwap(false, true) {
+			concurrent.Store(true)
+		}
+		time.Sleep(time.Millisecond)
+		inUse.Store(false)
+	}).Return(nil, nil)
+	tx.Mock.On("Commit", mock.Anything).Return(nil).Once()
+
+	slowHandler := func(ctx context.Context, tx adapter.Tx, j *Job) error {
+		for i := 0; i < 20; i++ {
+			if _, err := tx.Exec(ctx, "INSERT INTO side_effects"); err != nil {
+				return err
+			}
+			queries.Add(1)
+		}
+		return nil
+	}
+
+	j := &Job{Type: "MyJobTx", tx: tx, client: c, backoff: c.backoff, logger: c.logger, now: c.now}
+	w, err := NewWorker(
+		c, dummyWM,
+		WithWorkerWorkMapTx(WorkMapTx{"MyJobTx": slowHandler}),
+		WithWorkerHeartbeat(time.Millisecond),
+		withWorkerPollFunc(func(context.Context, string) (*Job, error) { return j, nil }),
+	)
+	require.NoError(t, err)
+
+	outcome, err := w.WorkOneOutcome(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, JobOutcomeSucceeded, outcome)
+	assert.Equal(t, int64(20), queries.Load())
+	assert.False(t, concurrent.Load())
+	tx.Mock.AssertExpectations(t)
+}
+
+func TestWorkerWorkMapTxCrash(t *testing.T) {
+	for name, openFunc := range adapterTesting.AllAdaptersOpenTestPool {
+		t.Run(name, func(t *testing.T) {
+			testWorkerWorkMapTxCrash(t, openFunc(t))
+		})
+	}
+}
+
+func testWorkerWorkMapTxCrash(t *testing.T, connPool adapter.ConnPool) {
+	ctx := context.Background()
+
+	c, err := NewClient(connPool)
+	require.NoError(t, err)
+
+	_, err = connPool.Exec(ctx, `
+CREATE TABLE IF NOT EXISTS gue_test_side_effects (queue text NOT NULL);
+TRUNCATE TABLE gue_test_side_effects;
+CREATE OR REPLACE FUNCTION gue_test_fail_delete() RETURNS trigger AS $$
+BEGIN
+  RAISE EXCEPTION 'process crashed before the job was deleted';
+END;
+$$ LANGUAGE plpgsql;
+DROP TRIGGER IF EXISTS gue_test_fail_delete ON gue_jobs;
+CREATE TRIGGER gue_test_fail_delete BEFORE DELETE ON gue_jobs FOR EACH ROW EXECUTE PROCEDURE gue_test_fail_delete();
+`)
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		_, err := connPool.Exec(ctx, `
+DROP TRIGGER IF EXISTS gue_test_fail_delete ON gue_jobs;
+DROP FUNCTION IF EXISTS gue_test_fail_delete();
+DROP TABLE IF EXISTS gue_test_side_effects;
+`)
+		assert.NoError(t, err)
+	})
+
+	err = c.EnqueueBatch(ctx, []*Job{{Type: "MyJob", Queue: "non-tx"}, {Type: "MyJob", Queue: "tx"}})
+	require.NoError(t, err)
+
+	// the side effect is written outside the job transaction
+	wNonTx, err := NewWorker(c, WorkMap{
+		"MyJob": func(ctx context.Context, j *Job) error {
+			_, err := connPool.Exec(ctx, `INSERT INTO gue_test_side_effects (queue) VALUES ($1)`, j.Queue)
+			return err
+		},
+	}, WithWorkerQueue("non-tx"))
+	require.NoError(t, err)
+
+	// the side effect is written within the job transaction
+	wTx, err := NewWorker(c, nil, WithWorkerQueue("tx"), WithWorkerWorkMapTx(WorkMapTx{
+		"MyJob": func(ctx context.Context, tx adapter.Tx, j *Job) error {
+			_, err := tx.Exec(ctx, `INSERT INTO gue_test_side_effects (queue) VALUES ($1)`, j.Queue)
+			return err
+		},
+	}))
+	require.NoError(t, err)
+
+	sideEffects := func(queue string) int {
+		var n int
+		err := connPool.QueryRow(ctx, `SELECT COUNT(*) FROM gue_test_side_effects WHERE queue = $1`, queue).Scan(&n)
+		require.NoError(t, err)
+		return n
+	}
+
+	// the job is worked, but deleting it fails the same way as if the process crashed between the two
+	for _, w := range []*Worker{wNonTx, wTx} {
+		outcome, err := w.WorkOneOutcome(ctx)
+		require.Error(t, err)
+		assert.Equal(t, JobOutcomeErrored, outcome)
+	}
+	assert.Equal(t, 1, sideEffects("non-tx"))
+	assert.Equal(t, 0, sideEffects("tx"))
+
+	// "restarted" process works the jobs that were not deleted once again
+	_, err = connPool.Exec(ctx, `DROP TRIGGER gue_test_fail_delete ON gue_jobs`)
+	require.NoError(t, err)
+
+	for _, w := range []*Worker{wNonTx, wTx} {
+		outcome, err := w.WorkOneOutcome(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, JobOutcomeSucceeded, outcome)
+	}
+	// non-transactional side effect happened twice, transactional one - exactly once
+	assert.Equal(t, 2, sideEffects("non-tx"))
+	assert.Equal(t, 1, sideEffects("tx"))
+}
+
+func TestWorkerUnknownJobPolicy(t *testing.T) {
+	ctx := context.Background()
+
+	c, err := NewClient(nil)
+	require.NoError(t, err)
+
+	errFallback := errors.New("could not forward the job")
+
+	for name, tc := range map[string]struct {
+		policy      UnknownJobPolicy
+		wf          WorkFunc
+		query       string
+		hookCalled  bool
+		fallbackErr error
+	}{
+		"retry": {
+			policy:     UnknownJobRetry(),
+			query:      "UPDATE gue_jobs SET error_count",
+			hookCalled: true,
+		},
+		"discard": {
+			policy:     UnknownJobDiscard(),
+			query:      "DELETE FROM gue_jobs",
+			hookCalled: true,
+		},
+		"dead letter": {
+			policy:     UnknownJobDeadLetter("dead-letter"),
+			query:      "UPDATE gue_jobs SET queue",
+			hookCalled: true,
+		},
+		"work func takes precedence succeeded": {
+			policy: UnknownJobDeadLetter("dead-letter"),
+			wf:     func(ctx context.Context, j *Job) error { return nil },
+			query:  "DELETE FROM gue_jobs",
+		},
+		"work func takes precedence errored": {
+			policy:      UnknownJobDiscard(),
+			wf:          func(ctx context.Context, j *Job) error { return errFallback },
+			query:       "UPDATE gue_jobs SET error_count",
+			fallbackErr: errFallback,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			tx := new(adapterTesting.Tx)
+			tx.Queryable.On("Exec", mock.Anything, mock.MatchedBy(func(query string) bool {
+				return strings.HasPrefix(query, tc.query)
+			}), mock.Anything).Return(nil, nil).Once()
+			var storedLastError any
+			if tc.hookCalled && tc.policy.action == unknownJobDiscard {
+				tx.Queryable.On("Exec", mock.Anything, mock.MatchedBy(func(query string) bool {
+					return strings.HasPrefix(query, "UPDATE gue_jobs SET last_error")
+				}), mock.Anything).Run(func(args mock.Arguments) {
+					storedLastError = args.Get(2).([]any)[0]
+				}).Return(nil, nil).Once()
+			}
+			tx.Mock.On("Commit", mock.Anything).Return(nil).Once()
+
+			j := &Job{Type: "unknown", tx: tx, client: c, backoff: c.backoff, logger: c.logger, now: c.now}
+
+			var hookErr error
+			w, err := NewWorker(
+				c, dummyWM,
+				WithWorkerUnknownJobPolicy(tc.policy),
+				WithWorkerUnknownJobWorkFunc(tc.wf),
+				WithWorkerHooksUnknownJobType(func(ctx context.Context, j *Job, err error) {
+					hookErr = err
+				}),
+				withWorkerPollFunc(func(context.Context, string) (*Job, error) { return j, nil }),
+			)
+			require.NoError(t, err)
+
+			outcome, err := w.WorkOneOutcome(ctx)
+			tx.Queryable.AssertExpectations(t)
+			tx.Mock.AssertExpectations(t)
+
+			if !tc.hookCalled {
+				assert.NoError(t, hookErr)
+				if tc.fallbackErr != nil {
+					assert.Equal(t, JobOutcomeErrored, outcome)
+					assert.ErrorIs(t, err, tc.fallbackErr)
+				} else {
+					assert.Equal(t, JobOutcomeSucceeded, outcome)
+				}
+				return
+			}
+
+			assert.Equal(t, JobOutcomeUnknownType, outcome)
+			assert.ErrorContains(t, hookErr, `unknown job type: "unknown"`)
+			if tc.policy.action == unknownJobDiscard {
+				// discarded job last error is stored before the job is deleted, the job itself is not mutated
+				assert.Equal(t, hookErr.Error(), storedLastError)
+				assert.False(t, j.LastError.Valid)
+			}
+		})
+	}
+}
+
+func TestWorkerUnknownJobPolicyDeadLetter(t *testing.T) {
+	for name, openFunc := range adapterTesting.AllAdaptersOpenTestPool {
+		t.Run(name, func(t *testing.T) {
+			testWorkerUnknownJobPolicyDeadLetter(t, openFunc(t))
+		})
+	}
+}
+
+func testWorkerUnknownJobPolicyDeadLetter(t *testing.T, connPool adapter.ConnPool) {
+	ctx := context.Background()
+
+	c, err := NewClient(connPool)
+	require.NoError(t, err)
+
+	job := &Job{Type: "unknown", Queue: "removed-handler", Args: []byte(`{"foo":"bar"}`)}
+	err = c.Enqueue(ctx, job)
+	require.NoError(t, err)
+
+	w, err := NewWorker(
+		c, dummyWM,
+		WithWorkerQueue("removed-handler"),
+		WithWorkerUnknownJobPolicy(UnknownJobDeadLetter("dead-letter")),
+	)
+	require.NoError(t, err)
+	require.True(t, w.WorkOne(ctx))
+
+	// job is not retried in the original queue
+	j, err := c.LockJob(ctx, "removed-handler")
+	require.NoError(t, err)
+	assert.Nil(t, j)
+
+	j, err = c.LockJob(ctx, "dead-letter")
+	require.NoError(t, err)
+	require.NotNil(t, j)
+
+	t.Cleanup(func() {
+		err := j.Done(ctx)
+		assert.NoError(t, err)
+	})
+
+	assert.Equal(t, job.ID, j.ID)
+	assert.Equal(t, []byte(`{"foo":"bar"}`), j.Args)
+	assert.Equal(t, int32(1), j.ErrorCount)
+	assert.Contains(t, j.LastError.String, `unknown job type: "unknown"`)
+}